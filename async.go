@@ -0,0 +1,127 @@
+package glogrus
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AsyncPolicy tells what an Async does with an entry when its buffer is full
+type AsyncPolicy int
+
+const (
+	// AsyncBlock waits for room in the buffer, slowing down the request
+	AsyncBlock AsyncPolicy = iota
+	// AsyncDrop drops the entry, the number of dropped entries is logged periodically
+	AsyncDrop
+)
+
+// asyncReportInterval is how often an Async logs the number of entries it dropped
+const asyncReportInterval = 10 * time.Second
+
+// asyncEntry is an entry waiting to be logged
+type asyncEntry struct {
+	entry *logrus.Entry
+	level logrus.Level
+	msg   string
+}
+
+// Async logs the req_start and req_served lines from a background goroutine,
+// so that requests do not wait for slow log outputs. It is given to the middleware
+// WithAsync and must be closed to flush the pending entries, e.g. on shutdown
+type Async struct {
+	policy  AsyncPolicy
+	ch      chan asyncEntry
+	done    chan struct{}
+	dropped uint64
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewAsync returns an Async buffering up to bufferSize entries and applying
+// the policy when the buffer is full. It starts the background goroutine
+func NewAsync(bufferSize int, policy AsyncPolicy) *Async {
+	a := &Async{
+		policy: policy,
+		ch:     make(chan asyncEntry, bufferSize),
+		done:   make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// log queues the entry. Once the Async is closed entries are logged synchronously
+func (a *Async) log(e *logrus.Entry, level logrus.Level, msg string) {
+	if !e.Logger.IsLevelEnabled(level) {
+		return
+	}
+	// the entry is timestamped now and not when it is logged
+	ae := asyncEntry{entry: e.WithTime(time.Now()), level: level, msg: msg}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		ae.entry.Log(level, msg)
+		return
+	}
+	if a.policy == AsyncBlock {
+		a.ch <- ae
+		return
+	}
+	select {
+	case a.ch <- ae:
+	default:
+		atomic.AddUint64(&a.dropped, 1)
+	}
+}
+
+// run logs the queued entries until the Async is closed
+func (a *Async) run() {
+	defer close(a.done)
+	ticker := time.NewTicker(asyncReportInterval)
+	defer ticker.Stop()
+
+	var last *logrus.Entry
+	for {
+		select {
+		case ae, ok := <-a.ch:
+			if !ok {
+				a.reportDropped(last)
+				return
+			}
+			ae.entry.Log(ae.level, ae.msg)
+			last = ae.entry
+		case <-ticker.C:
+			a.reportDropped(last)
+		}
+	}
+}
+
+// reportDropped logs the number of entries dropped since the last report, if any,
+// through the logger of the last logged entry
+func (a *Async) reportDropped(last *logrus.Entry) {
+	n := atomic.SwapUint64(&a.dropped, 0)
+	if n == 0 || last == nil {
+		return
+	}
+	last.Logger.WithField("dropped", n).Warn("glogrus: dropped access log entries")
+}
+
+// Close logs the pending entries and stops the background goroutine.
+// The entries logged afterwards are logged synchronously
+func (a *Async) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.ch)
+	a.mu.Unlock()
+
+	<-a.done
+	return nil
+}
@@ -0,0 +1,103 @@
+package glogrus
+
+import (
+	"encoding/base64"
+	"io"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ellipsis marks a truncated body
+const ellipsis = "..."
+
+// bodyBuffer keeps up to max bytes of a body
+type bodyBuffer struct {
+	max       int
+	buf       []byte
+	truncated bool
+}
+
+// write keeps what still fits of p
+func (b *bodyBuffer) write(p []byte) {
+	room := b.max - len(b.buf)
+	if len(p) > room {
+		p = p[:room]
+		b.truncated = true
+	}
+	b.buf = append(b.buf, p...)
+}
+
+// addBody adds the kept body to the fields, as the name field when it is valid UTF-8
+// and base64 encoded as the name+"_base64" field otherwise. Truncated bodies end with an ellipsis,
+// the rune cut by the truncation is dropped
+func (b *bodyBuffer) addBody(fields logrus.Fields, name string) {
+	if len(b.buf) == 0 && !b.truncated {
+		return
+	}
+	buf := b.buf
+	if b.truncated {
+		buf = trimPartialRune(buf)
+	}
+	if utf8.Valid(buf) {
+		body := string(buf)
+		if b.truncated {
+			body += ellipsis
+		}
+		fields[name] = body
+		return
+	}
+	body := base64.StdEncoding.EncodeToString(b.buf)
+	if b.truncated {
+		body += ellipsis
+	}
+	fields[name+"_base64"] = body
+}
+
+// trimPartialRune drops the incomplete UTF-8 sequence p ends with, if any
+func trimPartialRune(p []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(p); i++ {
+		if utf8.RuneStart(p[len(p)-i]) {
+			if !utf8.FullRune(p[len(p)-i:]) {
+				return p[:len(p)-i]
+			}
+			return p
+		}
+	}
+	return p
+}
+
+// bodyReader tees the request body into a bodyBuffer while the handler reads it
+type bodyReader struct {
+	io.ReadCloser
+	bodyBuffer
+}
+
+// Read reads from the request body and keeps what it can of the bytes read
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.write(p[:n])
+	return n, err
+}
+
+// textContentType reports whether a body of the Content-Type is worth logging,
+// i.e. the type is textual or unknown
+func textContentType(ct string) bool {
+	if ct == "" {
+		return true
+	}
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mt, "text/") || strings.HasSuffix(mt, "+json") || strings.HasSuffix(mt, "+xml") {
+		return true
+	}
+	switch mt {
+	case "application/json", "application/xml", "application/javascript", "application/x-www-form-urlencoded":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package glogrus
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTrimPartialRune(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"", ""},
+		{"abc", "abc"},
+		{"éé", "éé"},
+		{"é\xc3", "é"},
+		{"a\xe2\x82", "a"},
+		{"a€", "a€"},
+		{"\xff\xfe", "\xff\xfe"},
+	}
+	for _, tt := range tests {
+		if got := string(trimPartialRune([]byte(tt.in))); got != tt.want {
+			t.Errorf("trimPartialRune(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTruncatedUTF8Body(t *testing.T) {
+	body := strings.Repeat("é", 10)
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		w.Write(b)
+	}
+
+	l, buf := newTestLogger()
+	serve(l, echo, httptest.NewRequest("POST", "/", strings.NewReader(body)),
+		WithRequestBody(5), WithResponseBody(5))
+	line := servedLine(t, buf)
+	for _, name := range []string{"request_body", "response_body"} {
+		if line[name] != "éé..." {
+			t.Errorf("%s = %v, want éé...", name, line[name])
+		}
+		if v, ok := line[name+"_base64"]; ok {
+			t.Errorf("%s_base64 = %v, want none", name, v)
+		}
+	}
+}
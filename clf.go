@@ -0,0 +1,118 @@
+package glogrus
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// clfTimeLayout is the layout of the timestamp in the Common Log Format
+const clfTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// accessLog writes the single line access log, Common Log Format or similar,
+// either through the logger or to its own writer
+type accessLog struct {
+	format func(e *accessLogEntry) string
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// accessLogEntry holds what is known of a served request
+type accessLogEntry struct {
+	r      *http.Request
+	remote string
+	uri    string
+	start  time.Time
+	status int
+	bytes  int
+}
+
+// log writes the access log line of the request
+func (a *accessLog) log(c *config, l logrus.FieldLogger, e *accessLogEntry) {
+	line := a.format(e)
+	if a.w == nil {
+		c.emit(l, nil, logrus.InfoLevel, line)
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	io.WriteString(a.w, line+"\n")
+}
+
+// commonLogFormat formats the request in the Common Log Format:
+// remote - - [timestamp] "METHOD uri proto" status bytes
+func commonLogFormat(e *accessLogEntry) string {
+	return string(appendCommonLog(make([]byte, 0, 128), e, "-"))
+}
+
+// combinedLogFormat formats the request in the Combined Log Format:
+// remote - user [timestamp] "METHOD uri proto" status bytes "referer" "user-agent"
+func combinedLogFormat(e *accessLogEntry) string {
+	user := "-"
+	if u := e.r.URL.User; u != nil && u.Username() != "" {
+		user = u.Username()
+	}
+	b := appendCommonLog(make([]byte, 0, 256), e, user)
+	b = append(b, ' ')
+	b = appendQuoted(b, e.r.Referer())
+	b = append(b, ' ')
+	b = appendQuoted(b, e.r.UserAgent())
+	return string(b)
+}
+
+// appendCommonLog appends the Common Log Format line of the request to b
+func appendCommonLog(b []byte, e *accessLogEntry, user string) []byte {
+	b = append(b, clfHost(e.remote)...)
+	b = append(b, " - "...)
+	b = append(b, user...)
+	b = append(b, " ["...)
+	b = e.start.AppendFormat(b, clfTimeLayout)
+	b = append(b, "] \""...)
+	b = append(b, e.r.Method...)
+	b = append(b, ' ')
+	b = append(b, e.uri...)
+	b = append(b, ' ')
+	b = append(b, e.r.Proto...)
+	b = append(b, "\" "...)
+	b = strconv.AppendInt(b, int64(e.status), 10)
+	b = append(b, ' ')
+	if e.bytes == 0 {
+		b = append(b, '-')
+	} else {
+		b = strconv.AppendInt(b, int64(e.bytes), 10)
+	}
+	return b
+}
+
+// appendQuoted appends the value between double quotes to b, escaping the quotes
+// and the backslashes of the value. An empty value is appended as "-"
+func appendQuoted(b []byte, v string) []byte {
+	if v == "" {
+		return append(b, `"-"`...)
+	}
+	b = append(b, '"')
+	for i := 0; i < len(v); i++ {
+		if v[i] == '"' || v[i] == '\\' {
+			b = append(b, '\\')
+		}
+		b = append(b, v[i])
+	}
+	return append(b, '"')
+}
+
+// clfHost returns the host of the remote address, without the port
+func clfHost(remote string) string {
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		return host
+	}
+	if remote == "" {
+		return "-"
+	}
+	return remote
+}
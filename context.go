@@ -0,0 +1,39 @@
+package glogrus
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ContextKey is the key under which the request *logrus.Entry is stored in the Context
+// when the middleware is configured WithContextEntry. As a type of its own it cannot
+// collide with the keys set by other packages, FromContext and ContextWithEntry
+// are the way to read and set the entry
+type ContextKey struct{}
+
+// defaultEntry is returned by FromContext when the Context does not carry an entry
+var defaultEntry = logrus.NewEntry(logrus.StandardLogger())
+
+// FromContext returns the *logrus.Entry stored in the Context by the middleware.
+// It is pre-populated with the req_id, app, method and uri fields of the request.
+// When the Context carries no entry, e.g. outside of a request, an entry of the
+// logrus standard logger is returned, so the result is never nil
+//
+// Example:
+//
+//	func yourHandler(w http.ResponseWriter, r *http.Request) {
+//		glogrus.FromContext(r.Context()).Info("did a thing")
+//	}
+func FromContext(ctx context.Context) *logrus.Entry {
+	if e, ok := ctx.Value(ContextKey{}).(*logrus.Entry); ok && e != nil {
+		return e
+	}
+	return defaultEntry
+}
+
+// ContextWithEntry returns a copy of ctx carrying e, as the middleware does
+// WithContextEntry, e.g. to pass a logger to the handlers in tests
+func ContextWithEntry(ctx context.Context, e *logrus.Entry) context.Context {
+	return context.WithValue(ctx, ContextKey{}, e)
+}
@@ -0,0 +1,249 @@
+package glogrus
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// redacted replaces the values that must not be logged
+const redacted = "REDACTED"
+
+// withFields renames the fields configured WithFieldNames and
+// returns an entry of the logger carrying them
+func (c *config) withFields(l logrus.FieldLogger, fields logrus.Fields) *logrus.Entry {
+	for key, name := range c.fieldNames {
+		if v, ok := fields[key]; ok {
+			delete(fields, key)
+			fields[name] = v
+		}
+	}
+	return l.WithFields(fields)
+}
+
+// requestFields returns the fields returned for the request by the
+// functions configured WithRequestFields, nil when there are none
+func (c *config) requestFields(r *http.Request) logrus.Fields {
+	var fields logrus.Fields
+	for _, f := range c.fieldFuncs {
+		extra := f(r)
+		if len(extra) == 0 {
+			continue
+		}
+		if fields == nil {
+			fields = make(logrus.Fields, len(extra))
+		}
+		addFields(fields, extra)
+	}
+	return fields
+}
+
+// addFields copies the src fields to dst
+func addFields(dst, src logrus.Fields) {
+	for k, v := range src {
+		dst[k] = v
+	}
+}
+
+// appName returns the app name of the request, the one returned by the
+// WithAppNameFunc function when not empty, the static one otherwise
+func (c *config) appName(r *http.Request) string {
+	if c.nameFunc != nil {
+		if name := c.nameFunc(r); name != "" {
+			return name
+		}
+	}
+	return c.name
+}
+
+// addURI adds the requested URI to the fields, either as the "uri" field
+// or split into the "path" and "query" fields when configured WithSeparateQuery,
+// and the absolute URL as the "url" field when configured WithFullURL
+func (c *config) addURI(fields logrus.Fields, r *http.Request) {
+	if c.urlField {
+		fields["url"] = c.fullURL(r)
+	}
+	if c.separateQuery {
+		fields["path"] = r.URL.Path
+		fields["query"] = c.redactQuery(r.URL.RawQuery)
+		return
+	}
+	fields["uri"] = c.uri(r)
+}
+
+// fullURL returns the absolute URL of the request with the query parameters redacted,
+// the URI alone when it is already absolute or the request has no Host
+func (c *config) fullURL(r *http.Request) string {
+	uri := c.uri(r)
+	if r.Host == "" || r.URL.IsAbs() {
+		return uri
+	}
+	return c.scheme(r) + "://" + r.Host + uri
+}
+
+// uri returns the RequestURI with the query parameters redacted
+func (c *config) uri(r *http.Request) string {
+	uri := r.RequestURI
+	if i := strings.IndexByte(uri, '?'); i >= 0 {
+		uri = uri[:i+1] + c.redactQuery(uri[i+1:])
+	}
+	return uri
+}
+
+// redactQuery returns the raw query with the values of the parameters
+// configured WithRedactedQueryParams replaced by REDACTED.
+// The order of the parameters is preserved
+func (c *config) redactQuery(rawQuery string) string {
+	if len(c.redactParams) == 0 || rawQuery == "" {
+		return rawQuery
+	}
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		key := param
+		if j := strings.IndexByte(param, '='); j >= 0 {
+			key = param[:j]
+		}
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		if _, ok := c.redactParams[name]; ok {
+			params[i] = key + "=" + redacted
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// headerField maps a request header to the name of the field it is logged as
+type headerField struct {
+	header string
+	field  string
+}
+
+// headerFieldName returns the field name used for the header by WithHeaders,
+// e.g. "header_x_tenant_id" for "X-Tenant-ID"
+func headerFieldName(prefix, header string) string {
+	return prefix + strings.ToLower(strings.Replace(header, "-", "_", -1))
+}
+
+// setHeaderField logs the request header as the field, replacing the field
+// configured for the header by WithHeaders or WithHeaderFields, if any
+func (c *config) setHeaderField(header, field string) {
+	for i, hf := range c.headers {
+		if hf.header == header {
+			c.headers[i].field = field
+			return
+		}
+	}
+	c.headers = append(c.headers, headerField{header: header, field: field})
+}
+
+// addHeaders adds the configured request headers that are present to the fields,
+// multiple values are joined with commas
+func (c *config) addHeaders(fields logrus.Fields, h http.Header) {
+	for _, hf := range c.headers {
+		if _, ok := addressHeaders[hf.header]; ok && c.noRemote {
+			continue
+		}
+		if v, ok := h[hf.header]; ok {
+			fields[hf.field] = strings.Join(v, ",")
+		}
+	}
+}
+
+// addRespHeaders adds the configured response headers that were set
+// when the header was written to the fields
+func (c *config) addRespHeaders(fields logrus.Fields, h http.Header) {
+	for _, hf := range c.respHeaders {
+		if v, ok := h[hf.header]; ok {
+			fields[hf.field] = strings.Join(v, ",")
+		}
+	}
+}
+
+// addLatency adds the latency of the request to the fields, as a formatted
+// "latency" string by default, or as any of a numeric field named after the unit
+// configured WithLatencyUnit, the "duration" field configured WithDurationLatency
+// and the "latency_ns" field configured WithNanosecondLatency
+func (c *config) addLatency(fields logrus.Fields, d time.Duration) {
+	if c.latencyUnit == 0 && !c.durationLatency && !c.nanoLatency {
+		// same as fmt.Sprintf("%6.4f ms", ...) with a single allocation
+		var buf [32]byte
+		b := strconv.AppendFloat(buf[:0], float64(d)/float64(time.Millisecond), 'f', 4, 64)
+		fields["latency"] = string(append(b, " ms"...))
+		return
+	}
+	if c.latencyUnit > 0 {
+		fields[latencyFieldName(c.latencyUnit)] = float64(d) / float64(c.latencyUnit)
+	}
+	if c.durationLatency {
+		fields["duration"] = d.String()
+	}
+	if c.nanoLatency {
+		fields["latency_ns"] = d.Nanoseconds()
+	}
+}
+
+// latencyFieldName returns the name of the numeric latency field for the unit
+func latencyFieldName(unit time.Duration) string {
+	switch unit {
+	case time.Nanosecond:
+		return "latency_ns"
+	case time.Microsecond:
+		return "latency_us"
+	case time.Millisecond:
+		return "latency_ms"
+	case time.Second:
+		return "latency_s"
+	}
+	return "latency_" + unit.String()
+}
+
+// cookies returns the cookies of the request joined with commas, only their names
+// unless configured WithCookieValues, in which case the values of the redacted
+// cookies are replaced by REDACTED
+func (c *config) cookies(r *http.Request) string {
+	cookies := r.Cookies()
+	parts := make([]string, 0, len(cookies))
+	for _, ck := range cookies {
+		if !c.cookieValues {
+			parts = append(parts, ck.Name)
+			continue
+		}
+		value := ck.Value
+		if _, ok := c.redactCookies[ck.Name]; ok {
+			value = redacted
+		}
+		parts = append(parts, ck.Name+"="+value)
+	}
+	return strings.Join(parts, ",")
+}
+
+// addTimestamp adds t formatted as configured WithTimestamp to the fields
+func (c *config) addTimestamp(fields logrus.Fields, t time.Time) {
+	if c.tsField != "" {
+		fields[c.tsField] = t.Format(c.tsLayout)
+	}
+}
+
+// sizeUnits are the SI units of humanSize
+var sizeUnits = []string{"kB", "MB", "GB", "TB"}
+
+// humanSize formats a number of bytes with SI units and one decimal, e.g. "1.2 MB".
+// Sizes below 1 kB are formatted as bytes, e.g. "0 B", and above 1000 TB as TB
+func humanSize(n int64) string {
+	if n < 1000 {
+		return strconv.FormatInt(n, 10) + " B"
+	}
+	v := float64(n) / 1000
+	unit := 0
+	for v >= 999.95 && unit < len(sizeUnits)-1 {
+		v /= 1000
+		unit++
+	}
+	return strconv.FormatFloat(v, 'f', 1, 64) + " " + sizeUnits[unit]
+}
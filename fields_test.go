@@ -0,0 +1,29 @@
+package glogrus
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestDefaultLatency(t *testing.T) {
+	c := newConfig(nil)
+	for _, d := range []time.Duration{0, 1, 1234567, 25 * time.Millisecond, 3 * time.Hour, -time.Millisecond} {
+		fields := logrus.Fields{}
+		c.addLatency(fields, d)
+		if want := fmt.Sprintf("%6.4f ms", float64(d)/float64(time.Millisecond)); fields["latency"] != want {
+			t.Errorf("latency of %v = %q, want %q", d, fields["latency"], want)
+		}
+	}
+}
+
+func BenchmarkDefaultLatency(b *testing.B) {
+	c := newConfig(nil)
+	fields := logrus.Fields{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.addLatency(fields, 1234567)
+	}
+}
@@ -2,7 +2,6 @@ package glogrus
 
 import (
 	"context"
-	"fmt"
 	"net/http"
 	"time"
 
@@ -69,35 +68,334 @@ func NewGlogrus(l *logrus.Logger, name string) func(http.Handler) http.Handler {
 //		}
 //
 func NewGlogrusWithReqId(l *logrus.Logger, name string, reqidf func(context.Context) string) func(http.Handler) http.Handler {
+	return NewGlogrusWithOptions(l, WithAppName(name), WithRequestID(reqidf))
+}
+
+// NewGlogrusWithOptions allows you to configure a goji middleware that logs all requests and responses
+// using the structured logger logrus. It takes a logrus.FieldLogger (either a *logrus.Logger or a
+// *logrus.Entry carrying service-wide fields) and any number of Options as the parameters
+// and returns a middleware of type "func(goji.Handler) goji.Handler"
+//
+// Without any Option it behaves exactly like NewGlogrus with an empty app name.
+//
+// Example:
+//
+//		package main
+//
+//		import(
+//			"goji.io"
+//			"github.com/goji/glogrus2"
+//			"github.com/Sirupsen/logrus"
+//		)
+//
+//		func main() {
+//
+//			logr := logrus.New()
+//			logr.Formatter = new(logrus.JSONFormatter)
+//			goji.Use(glogrus.NewGlogrusWithOptions(logr,
+//				glogrus.WithAppName("my-app-name"),
+//				glogrus.WithRequestID(GetRequestId),
+//			))
+//
+//			goji.Get("/ping", yourHandler)
+//			goji.Serve()
+//		}
+//
+func NewGlogrusWithOptions(l logrus.FieldLogger, opts ...Option) func(http.Handler) http.Handler {
+	c := newConfig(opts)
+	al := c.accessLogger(l)
+	if tl := c.teeLogger(l); tl != nil {
+		c.extraLoggers = append(c.extraLoggers, tl)
+	}
+	c.reuseFields = copiesFields(al)
+	for _, extra := range c.extraLoggers {
+		c.reuseFields = c.reuseFields && copiesFields(extra)
+	}
 	return func(h http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
+			logged := !c.skip(r)
+			if !logged && (c.observer == nil || c.observeLoggedOnly) {
+				h.ServeHTTP(w, r)
+				return
+			}
+
 			ctx := r.Context()
-			start := time.Now()
+			start := c.now()
+
+			reqID := c.requestID(w, r)
+			extra := c.requestFields(r)
+			app := c.appName(r)
+			remote, proxy := c.remoteAddr(r)
+			var seq int64
+			if c.seqField && logged {
+				seq = nextSeq()
+			}
 
-			reqID := reqidf(ctx)
+			if logged {
+				for _, f := range c.beforeHooks {
+					f(r)
+				}
+			}
 
-			l.WithFields(logrus.Fields{
-				"req_id": reqID,
-				"uri":    r.RequestURI,
-				"method": r.Method,
-				"remote": r.RemoteAddr,
-			}).Info("req_start")
-			lresp := wrapWriter(w)
+			// when logging the request depends on its status, the req_start
+			// line is kept and only logged once the request is served
+			debug := c.debugRequest(r)
+			sampled := debug || !c.skipUnlessError(r) && c.sample(r)
+			deferStart := !debug && (!sampled || c.minStatus > 0)
+			base := c.baseLevel(r.Method)
+			startLevel := base
+			if debug {
+				startLevel = logrus.DebugLevel
+			}
+			var startFields logrus.Fields
+			if logged && (!c.noStartLog || debug || c.singleLine) && c.accessLog == nil {
+				startFields = c.newFields()
+				startFields["req_id"] = reqID
+				startFields["method"] = r.Method
+				if seq > 0 {
+					startFields["seq"] = seq
+				}
+				c.addRemote(startFields, r, remote, proxy)
+				c.addURI(startFields, r)
+				if c.host {
+					startFields["host"] = r.Host
+				}
+				c.addTimestamp(startFields, start)
+				addFields(startFields, c.staticFields)
+				addFields(startFields, extra)
+				if !deferStart && !c.singleLine {
+					c.emit(al, startFields, startLevel, c.startMsg)
+					c.putFields(startFields)
+					startFields = nil
+				}
+			}
+			lresp := wrapWriter(w, &c.writer)
 
-			h.ServeHTTP(lresp, r)
+			var reqBody *bodyReader
+			if c.reqBodyMax > 0 && r.Body != nil && r.Body != http.NoBody {
+				reqBody = &bodyReader{ReadCloser: r.Body, bodyBuffer: bodyBuffer{max: c.reqBodyMax}}
+				r.Body = reqBody
+			}
+
+			if c.ctxEntry {
+				fields := logrus.Fields{
+					"req_id": reqID,
+					"app":    app,
+					"method": r.Method,
+				}
+				c.addURI(fields, r)
+				entry := c.withFields(l, fields)
+				r = r.WithContext(ContextWithEntry(ctx, entry))
+			}
+			var uncompressed *uncompressedCount
+			if c.uncompressed {
+				uncompressed = new(uncompressedCount)
+				r = r.WithContext(context.WithValue(r.Context(), uncompressedKey{}, uncompressed))
+			}
+
+			var handlerStart, handlerEnd time.Time
+			if c.handlerLatency {
+				handlerStart = c.now()
+			}
+			panicked := c.serve(h, lresp, r, l, reqID)
+			if c.handlerLatency {
+				handlerEnd = c.now()
+			}
 			lresp.maybeWriteHeader()
 
-			latency := float64(time.Since(start)) / float64(time.Millisecond)
-
-			l.WithFields(logrus.Fields{
-				"req_id":  reqID,
-				"status":  lresp.status(),
-				"method":  r.Method,
-				"uri":     r.RequestURI,
-				"remote":  r.RemoteAddr,
-				"latency": fmt.Sprintf("%6.4f ms", latency),
-				"app":     name,
-			}).Info("req_served")
+			end := c.now()
+			latency := end.Sub(start)
+			status := lresp.Status()
+
+			// the latency of an upgraded connection, e.g. a WebSocket, lasts
+			// until the hijack rather than until the connection is closed
+			at := lresp.hijackedAt()
+			upgraded := !at.IsZero() && (status == http.StatusSwitchingProtocols || status == 0 && r.Header.Get("Upgrade") != "")
+			if upgraded {
+				status = http.StatusSwitchingProtocols
+				latency = at.Sub(start)
+			}
+
+			defer c.observe(r, logged, RequestInfo{
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Status:    status,
+				Latency:   latency,
+				Bytes:     lresp.BytesWritten(),
+				RequestID: reqID,
+			})
+
+			if !logged || !debug && !c.logStatus(status, sampled) {
+				c.putFields(startFields)
+				return
+			}
+			if c.accessLog != nil {
+				c.accessLog.log(c, al, &accessLogEntry{
+					r:      r,
+					remote: remote,
+					uri:    c.uri(r),
+					start:  start,
+					status: status,
+					bytes:  lresp.BytesWritten(),
+				})
+				return
+			}
+			if startFields != nil && !c.singleLine {
+				c.emit(al, startFields, startLevel, c.startMsg)
+				c.putFields(startFields)
+				startFields = nil
+			}
+
+			fields := c.newFields()
+			defer c.putFields(fields)
+			fields["req_id"] = reqID
+			fields["status"] = status
+			fields["bytes"] = lresp.BytesWritten()
+			fields["method"] = r.Method
+			fields["app"] = app
+			if seq > 0 {
+				fields["seq"] = seq
+			}
+			if c.statusText {
+				text := http.StatusText(status)
+				if text == "" {
+					text = "Unknown"
+				}
+				fields["status_text"] = text
+			}
+			c.addRemote(fields, r, remote, proxy)
+			c.addURI(fields, r)
+			c.addLatency(fields, latency)
+			if deadline, ok := ctx.Deadline(); c.deadline && ok {
+				fields["deadline_ms"] = float64(deadline.Sub(start)) / float64(time.Millisecond)
+			}
+			if c.humanSizes {
+				fields["bytes_human"] = humanSize(int64(lresp.BytesWritten()))
+			}
+			if upgraded {
+				fields["upgraded"] = true
+			}
+			if c.handlerLatency {
+				fields["handler_ms"] = float64(handlerEnd.Sub(handlerStart)) / float64(time.Millisecond)
+			}
+			if fb := lresp.firstByte(); c.ttfb && !fb.IsZero() {
+				fields["ttfb_ms"] = float64(fb.Sub(start)) / float64(time.Millisecond)
+			}
+			if c.contentLength {
+				fields["bytes_in"] = r.ContentLength
+				if c.humanSizes && r.ContentLength >= 0 {
+					fields["bytes_in_human"] = humanSize(r.ContentLength)
+				}
+			}
+			if c.schemeField {
+				fields["scheme"] = c.scheme(r)
+			}
+			if c.host {
+				fields["host"] = r.Host
+			}
+			if c.proto {
+				fields["proto"] = r.Proto
+			}
+			if c.tlsInfo {
+				addTLS(fields, r.TLS)
+			}
+			if c.userAgent {
+				fields["user_agent"] = r.UserAgent()
+			}
+			if c.referer {
+				fields["referer"] = r.Referer()
+			}
+			c.addHeaders(fields, r.Header)
+			if c.cookieNames {
+				fields["cookies"] = c.cookies(r)
+			}
+			c.addRespHeaders(fields, lresp.headers())
+			if c.redirectTarget && status >= 300 && status < 400 {
+				if loc := lresp.headers().Get("Location"); loc != "" {
+					fields["location"] = loc
+				}
+			}
+			if reqBody != nil {
+				reqBody.addBody(fields, "request_body")
+			}
+			if respBody := lresp.body(); respBody != nil {
+				respBody.addBody(fields, "response_body")
+			}
+			if c.flushes && lresp.flushed() {
+				fields["flushed"] = true
+			}
+			if ct := lresp.contentType(); c.respContentType && ct != "" {
+				fields["content_type"] = ct
+			}
+			if c.respEncoding {
+				if enc := lresp.contentEncoding(); enc != "" {
+					fields["content_encoding"] = enc
+				} else if c.logIdentity {
+					fields["content_encoding"] = "identity"
+				}
+			}
+			if c.uncompressed {
+				uncompressed.addTo(fields, lresp)
+			}
+			c.addTimestamp(fields, end)
+			if c.startTime {
+				fields["started_at"] = start.Format(time.RFC3339Nano)
+			}
+			addFields(fields, c.staticFields)
+			addFields(fields, extra)
+			for key, v := range startFields {
+				if _, ok := fields[key]; !ok {
+					fields[key] = v
+				}
+			}
+			c.putFields(startFields)
+
+			level := c.servedLevel(r.Method, status, base)
+			if c.slowThreshold > 0 && latency > c.slowThreshold {
+				fields["slow"] = true
+				level = moreSevere(level, logrus.WarnLevel)
+			}
+			if deadline, ok := ctx.Deadline(); c.budgetWarn > 0 && ok {
+				if budget := deadline.Sub(start); budget <= 0 || float64(latency)/float64(budget) > c.budgetWarn {
+					fields["budget_exceeded"] = true
+					level = moreSevere(level, logrus.WarnLevel)
+				}
+			}
+			if panicked != "" {
+				fields["panic"] = panicked
+				level = moreSevere(level, logrus.ErrorLevel)
+			}
+			if c.errorf != nil {
+				if err := c.errorf(r.Context()); err != nil {
+					fields[logrus.ErrorKey] = err
+					level = moreSevere(level, logrus.ErrorLevel)
+				}
+			}
+			if c.disconnects {
+				switch ctx.Err() {
+				case context.Canceled:
+					fields["client_disconnected"] = true
+					level = moreSevere(level, logrus.WarnLevel)
+				case context.DeadlineExceeded:
+					fields["timeout"] = true
+					level = moreSevere(level, logrus.WarnLevel)
+				}
+			}
+
+			if debug {
+				level = logrus.DebugLevel
+			}
+			if rl := c.errorLimit; rl != nil && level <= logrus.ErrorLevel {
+				ok, suppressed := rl.allow(c.errorLimitKey(r, status), end)
+				if !ok {
+					return
+				}
+				if suppressed > 0 {
+					fields["suppressed"] = suppressed
+				}
+			}
+
+			c.emit(al, fields, level, c.servedMsg)
 		}
 		return http.HandlerFunc(fn)
 	}
@@ -107,3 +405,51 @@ func NewGlogrusWithReqId(l *logrus.Logger, name string, reqidf func(context.Cont
 func emptyRequestId(ctx context.Context) string {
 	return ""
 }
+
+// emit logs the fields to l and to the loggers configured WithAdditionalLoggers,
+// the fields are renamed once and shared, as every entry copies them
+func (c *config) emit(l logrus.FieldLogger, fields logrus.Fields, level logrus.Level, msg string) {
+	c.logEntry(c.withFields(l, fields), level, msg)
+	for _, extra := range c.extraLoggers {
+		c.logEntry(extra.WithFields(fields), level, msg)
+	}
+}
+
+// logEntry logs the entry, from the background goroutine when configured WithAsync
+func (c *config) logEntry(e *logrus.Entry, level logrus.Level, msg string) {
+	if c.async != nil {
+		c.async.log(e, level, msg)
+		return
+	}
+	e.Log(level, msg)
+}
+
+// logStatus reports whether a request served with the status is logged,
+// given the WithErrorsOnly threshold and the WithSampling decision
+func (c *config) logStatus(status int, sampled bool) bool {
+	if status < c.minStatus {
+		return false
+	}
+	return sampled || status >= 400
+}
+
+// skipUnlessError reports whether the request is only logged when its status
+// is an error, as configured WithSkipPathsUnlessError
+func (c *config) skipUnlessError(r *http.Request) bool {
+	_, ok := c.skipOKPaths[r.URL.Path]
+	return ok
+}
+
+// skip reports whether logging is disabled for the request,
+// either by WithSkipPaths or by WithSkipFunc
+func (c *config) skip(r *http.Request) bool {
+	if _, ok := c.skipPaths[r.URL.Path]; ok {
+		return true
+	}
+	for _, f := range c.skipFuncs {
+		if f(r) {
+			return true
+		}
+	}
+	return false
+}
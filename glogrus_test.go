@@ -0,0 +1,136 @@
+package glogrus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newTestLogger returns a logger writing JSON lines to the returned buffer
+func newTestLogger() (*logrus.Logger, *bytes.Buffer) {
+	buf := new(bytes.Buffer)
+	l := logrus.New()
+	l.Out = buf
+	l.Formatter = new(logrus.JSONFormatter)
+	l.Level = logrus.DebugLevel
+	return l, buf
+}
+
+// logLines decodes the JSON lines written to buf
+func logLines(t testing.TB, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var lines []map[string]interface{}
+	sc := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(sc.Bytes(), &line); err != nil {
+			t.Fatalf("invalid log line %q: %v", sc.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// servedLine returns the req_served line written to buf
+func servedLine(t testing.TB, buf *bytes.Buffer) map[string]interface{} {
+	t.Helper()
+	for _, line := range logLines(t, buf) {
+		if line["msg"] == "req_served" {
+			return line
+		}
+	}
+	t.Fatalf("no req_served line in %q", buf.String())
+	return nil
+}
+
+// serve serves the request with the middleware configured with opts around h
+func serve(l logrus.FieldLogger, h http.HandlerFunc, r *http.Request, opts ...Option) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	NewGlogrusWithOptions(l, opts...)(h).ServeHTTP(w, r)
+	return w
+}
+
+// okHandler writes an empty 200 response
+func okHandler(w http.ResponseWriter, r *http.Request) {}
+
+func BenchmarkServe(b *testing.B) {
+	l := logrus.New()
+	l.Out = io.Discard
+	h := NewGlogrus(l, "app")(http.HandlerFunc(okHandler))
+	r := httptest.NewRequest("GET", "/path?query=1", nil)
+	w := httptest.NewRecorder()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(w, r)
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	upgrade := func(w http.ResponseWriter, r *http.Request) {
+		now = now.Add(2 * time.Millisecond)
+		if _, _, err := w.(http.Hijacker).Hijack(); err != nil {
+			t.Fatal(err)
+		}
+		// the connection is used long after the upgrade
+		now = now.Add(time.Hour)
+	}
+
+	l, buf := newTestLogger()
+	r := httptest.NewRequest("GET", "/ws", nil)
+	r.Header.Set("Connection", "Upgrade")
+	r.Header.Set("Upgrade", "websocket")
+	w := &hijackRecorder{headerRecorder{header: http.Header{}}}
+	NewGlogrusWithOptions(l, WithClock(clock), WithNumericLatency())(http.HandlerFunc(upgrade)).ServeHTTP(w, r)
+
+	line := servedLine(t, buf)
+	if line["status"] != float64(http.StatusSwitchingProtocols) {
+		t.Errorf("status = %v, want 101", line["status"])
+	}
+	if line["level"] != "info" {
+		t.Errorf("level = %v, want info", line["level"])
+	}
+	if line["upgraded"] != true {
+		t.Errorf("upgraded = %v, want true", line["upgraded"])
+	}
+	if line["latency_ms"] != float64(2) {
+		t.Errorf("latency_ms = %v, want 2", line["latency_ms"])
+	}
+	if len(w.codes) != 0 {
+		t.Errorf("statuses written after the hijack: %v", w.codes)
+	}
+}
+
+func TestHostHTTP2(t *testing.T) {
+	l, buf := newTestLogger()
+	srv := httptest.NewUnstartedServer(NewGlogrusWithOptions(l, WithHost())(http.HandlerFunc(okHandler)))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("protocol = %s, want HTTP/2", resp.Proto)
+	}
+	// HTTP/2 requests carry the :authority pseudo-header instead of Host
+	want := srv.Listener.Addr().String()
+	for _, line := range logLines(t, buf) {
+		if line["host"] != want {
+			t.Errorf("%v: host = %v, want %s", line["msg"], line["host"], want)
+		}
+	}
+}
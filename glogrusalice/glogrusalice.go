@@ -0,0 +1,20 @@
+// Package glogrusalice provides the glogrus middleware as an alice.Constructor,
+// ready to be chained with alice.New along with the other middlewares.
+package glogrusalice
+
+import (
+	"github.com/goji/glogrus2"
+	"github.com/justinas/alice"
+	"github.com/sirupsen/logrus"
+)
+
+// Constructor returns the middleware configured by glogrus.NewGlogrusWithOptions
+// as an alice.Constructor
+//
+// Example:
+//
+//	chain := alice.New(glogrusalice.Constructor(logr, glogrus.WithAppName("my-app-name")))
+//	http.ListenAndServe(":8080", chain.Then(yourHandler))
+func Constructor(l logrus.FieldLogger, opts ...glogrus.Option) alice.Constructor {
+	return alice.Constructor(glogrus.NewGlogrusWithOptions(l, opts...))
+}
@@ -0,0 +1,39 @@
+package glogrusalice_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goji/glogrus2"
+	"github.com/goji/glogrus2/glogrusalice"
+	"github.com/justinas/alice"
+	"github.com/sirupsen/logrus"
+)
+
+func TestConstructor(t *testing.T) {
+	var buf bytes.Buffer
+	l := logrus.New()
+	l.Out = &buf
+	l.Formatter = new(logrus.JSONFormatter)
+
+	h := alice.New(glogrusalice.Constructor(l, glogrus.WithAppName("app"), glogrus.WithoutStartLog())).
+		Then(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/tea", nil))
+	if w.Code != http.StatusTeapot {
+		t.Errorf("code = %d, want 418", w.Code)
+	}
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("invalid log line %q: %v", buf.String(), err)
+	}
+	if line["msg"] != "req_served" || line["status"] != float64(http.StatusTeapot) || line["uri"] != "/tea" {
+		t.Errorf("unexpected log line %v", line)
+	}
+}
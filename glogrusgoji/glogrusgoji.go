@@ -0,0 +1,28 @@
+// Package glogrusgoji logs the goji pattern matched for the request with glogrus.
+package glogrusgoji
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/goji/glogrus2"
+	"github.com/sirupsen/logrus"
+	"goji.io/middleware"
+)
+
+// WithPattern adds the goji pattern matched for the request (e.g. "/users/:id")
+// to both log lines as the "route" field. The field is omitted when goji did not
+// match any pattern, e.g. when the middleware is not installed on a goji Mux
+//
+// Example:
+//
+//	mux := goji.NewMux()
+//	mux.Use(glogrus.NewGlogrusWithOptions(logr, glogrusgoji.WithPattern()))
+func WithPattern() glogrus.Option {
+	return glogrus.WithRequestFields(func(r *http.Request) logrus.Fields {
+		if p, ok := middleware.Pattern(r.Context()).(fmt.Stringer); ok {
+			return logrus.Fields{"route": p.String()}
+		}
+		return nil
+	})
+}
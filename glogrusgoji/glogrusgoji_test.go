@@ -0,0 +1,47 @@
+package glogrusgoji_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goji/glogrus2"
+	"github.com/goji/glogrus2/glogrusgoji"
+	"github.com/sirupsen/logrus"
+	"goji.io"
+	"goji.io/pat"
+)
+
+func TestWithPattern(t *testing.T) {
+	var buf bytes.Buffer
+	l := logrus.New()
+	l.Out = &buf
+	l.Formatter = new(logrus.JSONFormatter)
+
+	mux := goji.NewMux()
+	mux.Use(glogrus.NewGlogrusWithOptions(l, glogrusgoji.WithPattern()))
+	mux.HandleFunc(pat.Get("/users/:id"), func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct{ path, route string }{
+		{"/users/42", "/users/:id"},
+		{"/missing", ""},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.path, nil))
+		sc := bufio.NewScanner(&buf)
+		for sc.Scan() {
+			var line map[string]interface{}
+			if err := json.Unmarshal(sc.Bytes(), &line); err != nil {
+				t.Fatalf("invalid log line %q: %v", sc.Text(), err)
+			}
+			route, ok := line["route"]
+			if tt.route == "" && ok || tt.route != "" && route != tt.route {
+				t.Errorf("%s %v: route = %v, want %q", tt.path, line["msg"], route, tt.route)
+			}
+		}
+	}
+}
@@ -0,0 +1,35 @@
+// Package glogrusotel adds the OpenTelemetry trace context to the lines logged by glogrus:
+// the trace and span ids of the span found in the request Context, correlating
+// the access log with the traces of the requests.
+package glogrusotel
+
+import (
+	"net/http"
+
+	"github.com/goji/glogrus2"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithTraceContext adds the "trace_id" and "span_id" fields of the span found
+// in the request Context to both log lines. The fields are omitted when
+// the Context carries no valid span
+//
+// Example:
+//
+//	goji.Use(glogrus.NewGlogrusWithOptions(logr, glogrusotel.WithTraceContext()))
+func WithTraceContext() glogrus.Option {
+	return glogrus.WithRequestFields(traceFields)
+}
+
+// traceFields returns the trace fields of the span found in the request Context
+func traceFields(r *http.Request) logrus.Fields {
+	sc := trace.SpanFromContext(r.Context()).SpanContext()
+	if !sc.IsValid() {
+		return nil
+	}
+	return logrus.Fields{
+		"trace_id": sc.TraceID().String(),
+		"span_id":  sc.SpanID().String(),
+	}
+}
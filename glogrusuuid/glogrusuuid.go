@@ -0,0 +1,19 @@
+// Package glogrusuuid generates random UUID request ids for glogrus, for the requests
+// reaching the middleware without an id.
+package glogrusuuid
+
+import (
+	"github.com/goji/glogrus2"
+	"github.com/google/uuid"
+)
+
+// WithUUIDRequestID generates a random (version 4) UUID request id when none is found
+// in the Context or in the request header. The generated id is logged on both lines
+// and set on the response as the X-Request-ID header
+//
+// Example:
+//
+//	goji.Use(glogrus.NewGlogrusWithOptions(logr, glogrusuuid.WithUUIDRequestID()))
+func WithUUIDRequestID() glogrus.Option {
+	return glogrus.WithRequestIDGenerator(uuid.NewString)
+}
@@ -0,0 +1,74 @@
+package glogrus
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// baseLevel returns the level of the req_start line of the request and the level
+// of its req_served line when the status is not an error: the level set for the method
+// WithMethodLevels, or the level set WithLevel or WithDynamicLevel for the other methods
+func (c *config) baseLevel(method string) logrus.Level {
+	if level, ok := c.methodLevels[method]; ok {
+		return level
+	}
+	if c.levelf != nil {
+		return c.levelf()
+	}
+	return c.level
+}
+
+// defaultStatusLevel maps 4xx responses to Warn and 5xx responses to Error, unless
+// the base level is more severe, every other status is logged at the base level
+func defaultStatusLevel(status int, base logrus.Level) logrus.Level {
+	switch {
+	case status >= 500:
+		return moreSevere(base, logrus.ErrorLevel)
+	case status >= 400:
+		return moreSevere(base, logrus.WarnLevel)
+	}
+	return base
+}
+
+// servedLevel returns the level of the req_served line for the given method and status,
+// base being the baseLevel of the request, evaluated once per request.
+// The level returned by the WithStatusLevel function is raised to the level of
+// the method when the method has a level set WithMethodLevels
+func (c *config) servedLevel(method string, status int, base logrus.Level) logrus.Level {
+	if c.statusLevel == nil {
+		return defaultStatusLevel(status, base)
+	}
+	level := c.statusLevel(status)
+	if ml, ok := c.methodLevels[method]; ok {
+		level = moreSevere(level, ml)
+	}
+	return level
+}
+
+// moreSevere returns the more severe of the two levels
+func moreSevere(a, b logrus.Level) logrus.Level {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// debugRequest reports whether the request carries the header set WithDebugHeader
+// with a truthy value ("1", "true", "yes", "on"...)
+func (c *config) debugRequest(r *http.Request) bool {
+	if c.debugHeader == "" {
+		return false
+	}
+	v := strings.TrimSpace(r.Header.Get(c.debugHeader))
+	if b, err := strconv.ParseBool(v); err == nil {
+		return b
+	}
+	switch strings.ToLower(v) {
+	case "yes", "y", "on":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package glogrus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestServedLevel(t *testing.T) {
+	tests := []struct {
+		method string
+		status int
+		want   logrus.Level
+	}{
+		{"GET", 200, logrus.InfoLevel},
+		{"GET", 404, logrus.WarnLevel},
+		{"GET", 503, logrus.ErrorLevel},
+		{"HEAD", 200, logrus.DebugLevel},
+		{"HEAD", 404, logrus.WarnLevel},
+		{"DELETE", 200, logrus.ErrorLevel},
+		{"DELETE", 404, logrus.ErrorLevel},
+		{"DELETE", 503, logrus.ErrorLevel},
+	}
+	c := newConfig([]Option{WithMethodLevels(map[string]logrus.Level{
+		"HEAD":   logrus.DebugLevel,
+		"DELETE": logrus.ErrorLevel,
+	})})
+	for _, tt := range tests {
+		if got := c.servedLevel(tt.method, tt.status, c.baseLevel(tt.method)); got != tt.want {
+			t.Errorf("servedLevel(%s, %d) = %v, want %v", tt.method, tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestMethodLevelBothLines(t *testing.T) {
+	l, buf := newTestLogger()
+	notFound := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) }
+	serve(l, notFound, httptest.NewRequest("DELETE", "/", nil),
+		WithMethodLevels(map[string]logrus.Level{"DELETE": logrus.ErrorLevel}))
+	for _, line := range logLines(t, buf) {
+		if line["level"] != "error" {
+			t.Errorf("%v logged at %v, want error", line["msg"], line["level"])
+		}
+	}
+}
+
+func TestDynamicLevelOncePerRequest(t *testing.T) {
+	calls := 0
+	level := logrus.InfoLevel
+	levelf := func() logrus.Level {
+		calls++
+		return level
+	}
+	l, buf := newTestLogger()
+	// the level changing during the request does not split its two lines
+	changing := func(w http.ResponseWriter, r *http.Request) { level = logrus.DebugLevel }
+	serve(l, changing, httptest.NewRequest("GET", "/", nil), WithDynamicLevel(levelf))
+	if calls != 1 {
+		t.Errorf("level function called %d times, want 1", calls)
+	}
+	for _, line := range logLines(t, buf) {
+		if line["level"] != "info" {
+			t.Errorf("%v: level = %v, want info", line["msg"], line["level"])
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package glogrus
+
+import (
+	"io"
+
+	"github.com/sirupsen/logrus"
+)
+
+// accessLogger returns the logger of the req_start and req_served lines,
+// l itself unless configured WithAccessLogWriter. The logger writing to the access
+// log writer inherits the formatter, the level and the fields of l
+func (c *config) accessLogger(l logrus.FieldLogger) logrus.FieldLogger {
+	if c.accessWriter == nil {
+		return l
+	}
+	return derivedLogger(l, c.accessWriter, c.accessFormatter)
+}
+
+// teeLogger returns the logger writing the text lines WithTee, nil when not configured
+func (c *config) teeLogger(l logrus.FieldLogger) logrus.FieldLogger {
+	if c.teeWriter == nil {
+		return nil
+	}
+	return derivedLogger(l, c.teeWriter, new(logrus.TextFormatter))
+}
+
+// derivedLogger returns a logger writing to w that inherits the formatter,
+// the level and the fields of l. The formatter is f instead, unless nil
+func derivedLogger(l logrus.FieldLogger, w io.Writer, f logrus.Formatter) logrus.FieldLogger {
+	dl := logrus.New()
+	dl.Out = w
+	var data logrus.Fields
+	switch base := l.(type) {
+	case *logrus.Logger:
+		dl.Formatter = base.Formatter
+		dl.Level = base.GetLevel()
+	case *logrus.Entry:
+		dl.Formatter = base.Logger.Formatter
+		dl.Level = base.Logger.GetLevel()
+		data = base.Data
+	}
+	if f != nil {
+		dl.Formatter = f
+	}
+	if len(data) > 0 {
+		return dl.WithFields(data)
+	}
+	return dl
+}
@@ -0,0 +1,30 @@
+package glogrus
+
+import (
+	"net/http"
+	"time"
+)
+
+// RequestInfo holds the measurements of a served request
+type RequestInfo struct {
+	Method    string
+	Path      string
+	Status    int
+	Latency   time.Duration
+	Bytes     int
+	RequestID string
+}
+
+// observe calls the observer set WithObserver and, for the logged requests,
+// the hooks set WithAfterHook once the request is served
+func (c *config) observe(r *http.Request, logged bool, info RequestInfo) {
+	if c.observer != nil {
+		c.observer(info)
+	}
+	if !logged {
+		return
+	}
+	for _, f := range c.afterHooks {
+		f(r, info)
+	}
+}
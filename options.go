@@ -0,0 +1,1033 @@
+package glogrus
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Option configures the middleware returned by NewGlogrusWithOptions
+type Option func(*config)
+
+// config holds the settings collected from the Options
+type config struct {
+	name        string
+	nameFunc    func(*http.Request) string
+	reqidf      func(context.Context) string
+	reqIDHeader string
+	reqIDGen    func() string
+	seqField    bool
+	level       logrus.Level
+	levelf      func() logrus.Level
+	now         func() time.Time
+	tsField     string
+	tsLayout    string
+	startTime   bool
+	startMsg    string
+	servedMsg   string
+
+	statusLevel  func(int) logrus.Level
+	statusText   bool
+	methodLevels map[string]logrus.Level
+	debugHeader  string
+	skipPaths    map[string]struct{}
+	skipFuncs    []func(*http.Request) bool
+	skipOKPaths  map[string]struct{}
+	noStartLog   bool
+	singleLine   bool
+	ctxEntry     bool
+	userAgent    bool
+	referer      bool
+	host         bool
+	proto        bool
+	tlsInfo      bool
+	schemeField  bool
+
+	contentLength bool
+	humanSizes    bool
+	separateQuery bool
+	urlField      bool
+	redactParams  map[string]struct{}
+	headers       []headerField
+	cookieNames   bool
+	cookieValues  bool
+	redactCookies map[string]struct{}
+
+	respContentType bool
+	respHeaders     []headerField
+	redirectTarget  bool
+	flushes         bool
+	uncompressed    bool
+	respEncoding    bool
+	logIdentity     bool
+	writer          writerOptions
+	latencyUnit     time.Duration
+	durationLatency bool
+	nanoLatency     bool
+	handlerLatency  bool
+	deadline        bool
+
+	recovery   bool
+	fieldNames map[string]string
+	reqBodyMax int
+
+	slowThreshold time.Duration
+	budgetWarn    float64
+	ttfb          bool
+	disconnects   bool
+	errorf        func(context.Context) error
+	errorLimit    *errorRateLimit
+	errorKeyf     func(*http.Request, int) string
+	sampleRate    float64
+	pathRates     []pathRate
+	minStatus     int
+
+	fieldFuncs   []func(*http.Request) logrus.Fields
+	staticFields logrus.Fields
+	env          string
+	accessLog    *accessLog
+
+	forwardedFor   bool
+	trustedProxies []*net.IPNet
+	noRemote       bool
+	remotePort     bool
+
+	accessWriter    io.Writer
+	accessFormatter logrus.Formatter
+	teeWriter       io.Writer
+	discard         bool
+	async           *Async
+	extraLoggers    []logrus.FieldLogger
+	reuseFields     bool
+
+	observer          func(RequestInfo)
+	observeLoggedOnly bool
+	beforeHooks       []func(*http.Request)
+	afterHooks        []func(*http.Request, RequestInfo)
+}
+
+// newConfig returns a config with the defaults used by NewGlogrus
+// and applies the given options on top of it
+func newConfig(opts []Option) *config {
+	c := &config{
+		reqidf:      emptyRequestId,
+		reqIDHeader: defaultRequestIDHeader,
+		level:       logrus.InfoLevel,
+		now:         time.Now,
+		startMsg:    "req_start",
+		servedMsg:   "req_served",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.writer.now = c.now
+	c.writer.recordFirstByte = c.ttfb
+	if c.discard {
+		c.accessWriter, c.teeWriter, c.extraLoggers = io.Discard, nil, nil
+		if c.accessLog != nil && c.accessLog.w != nil {
+			c.accessLog.w = io.Discard
+		}
+	}
+	if c.env != "" {
+		WithStaticFields(logrus.Fields{"env": c.env})(c)
+	}
+	return c
+}
+
+// WithAppName sets the value of the "app" field logged when a request is served
+func WithAppName(name string) Option {
+	return func(c *config) {
+		c.name = name
+	}
+}
+
+// WithAppNameFunc sets a function returning the app name of each request,
+// e.g. by product area. The app name set WithAppName, or given to the constructor,
+// is used when the function returns an empty string
+func WithAppNameFunc(f func(*http.Request) string) Option {
+	return func(c *config) {
+		c.nameFunc = f
+	}
+}
+
+// WithRequestID sets the function used to retrieve the request id from the Context.
+// A nil function retrieves an empty request id
+func WithRequestID(reqidf func(context.Context) string) Option {
+	return func(c *config) {
+		if reqidf == nil {
+			reqidf = emptyRequestId
+		}
+		c.reqidf = reqidf
+	}
+}
+
+// WithRequestIDHeader sets the request header used as request id when the one
+// retrieved from the Context is empty. Defaults to "X-Request-ID", an empty name
+// disables the fallback
+func WithRequestIDHeader(name string) Option {
+	return func(c *config) {
+		c.reqIDHeader = name
+	}
+}
+
+// WithGeneratedRequestID generates a random request id when none is found
+// in the Context or in the request header. The generated id is also set
+// on the response as the X-Request-ID header
+func WithGeneratedRequestID() Option {
+	return func(c *config) {
+		c.reqIDGen = newRequestID
+	}
+}
+
+// WithRequestIDGenerator is like WithGeneratedRequestID, with f generating the request ids
+// instead of the random hex ids. An empty id returned by f is logged as such
+func WithRequestIDGenerator(f func() string) Option {
+	return func(c *config) {
+		c.reqIDGen = f
+	}
+}
+
+// WithSequenceField numbers the requests, each one being logged with the next value
+// of a counter as the "seq" field of both lines. The counter is shared by all the
+// middlewares of the process and starts again at 1 when the process restarts, it
+// orders the requests of the process, not the requests of a connection
+func WithSequenceField() Option {
+	return func(c *config) {
+		c.seqField = true
+	}
+}
+
+// WithLevel sets the level at which the log lines are emitted. Defaults to logrus.InfoLevel
+func WithLevel(level logrus.Level) Option {
+	return func(c *config) {
+		c.level = level
+	}
+}
+
+// WithDynamicLevel sets a function returning the level at which the log lines are
+// emitted, called once per request, for both lines, instead of using the level set
+// WithLevel, e.g. to switch between Info and Debug from an admin endpoint without
+// restarting.
+// The function is called concurrently by the requests being served, it must be
+// safe for concurrent use, e.g. by loading a level stored in an atomic variable:
+//
+//	var accessLevel atomic.Uint32 // set with accessLevel.Store(uint32(logrus.DebugLevel))
+//
+//	glogrus.WithDynamicLevel(func() logrus.Level {
+//		return logrus.Level(accessLevel.Load())
+//	})
+func WithDynamicLevel(f func() logrus.Level) Option {
+	return func(c *config) {
+		c.levelf = f
+	}
+}
+
+// WithClock sets the function used to read the current time when a request starts
+// and when it is served. Defaults to time.Now, mostly useful in tests
+func WithClock(now func() time.Time) Option {
+	return func(c *config) {
+		c.now = now
+	}
+}
+
+// WithTimestamp adds the time read from the clock of the middleware to both log lines
+// as the fieldName field, formatted with layout (e.g. "@timestamp" and time.RFC3339Nano).
+// It does not depend on the timestamp added by the logrus formatter
+func WithTimestamp(fieldName, layout string) Option {
+	return func(c *config) {
+		c.tsField = fieldName
+		c.tsLayout = layout
+	}
+}
+
+// WithStartTime adds the time the request started, as used to compute the latency,
+// to the req_served line as the "started_at" field formatted as RFC3339Nano
+func WithStartTime() Option {
+	return func(c *config) {
+		c.startTime = true
+	}
+}
+
+// WithStartMessage sets the message of the line logged when a request starts.
+// Defaults to "req_start"
+func WithStartMessage(msg string) Option {
+	return func(c *config) {
+		c.startMsg = msg
+	}
+}
+
+// WithServedMessage sets the message of the line logged when a request is served.
+// Defaults to "req_served"
+func WithServedMessage(msg string) Option {
+	return func(c *config) {
+		c.servedMsg = msg
+	}
+}
+
+// WithStatusLevel sets the function that picks the level of the req_served line
+// from the response status. By default 4xx responses are logged at Warn,
+// 5xx responses at Error and everything else at the level set by WithLevel
+// or WithMethodLevels
+func WithStatusLevel(f func(status int) logrus.Level) Option {
+	return func(c *config) {
+		c.statusLevel = f
+	}
+}
+
+// WithStatusText adds the reason phrase of the response status, e.g. "Not Found",
+// to the req_served line as the "status_text" field. "Unknown" is logged
+// for the statuses without a standard reason phrase
+func WithStatusText() Option {
+	return func(c *config) {
+		c.statusText = true
+	}
+}
+
+// WithMethodLevels sets the level of both lines by request method, e.g. Info for
+// POST and Debug for GET. The methods that are not in the map use the level set
+// WithLevel. Error responses are still logged at the more severe level of the status
+func WithMethodLevels(levels map[string]logrus.Level) Option {
+	return func(c *config) {
+		if c.methodLevels == nil {
+			c.methodLevels = make(map[string]logrus.Level, len(levels))
+		}
+		for method, level := range levels {
+			c.methodLevels[method] = level
+		}
+	}
+}
+
+// WithDebugHeader logs the requests carrying the named header with a truthy value
+// ("1", "true", "yes", "on"...) at Debug level, whatever their method or status.
+// Both lines of such requests are always logged, even WithoutStartLog or when
+// they would be dropped by WithSampling or WithErrorsOnly.
+// The header is ignored unless this option is set
+func WithDebugHeader(name string) Option {
+	return func(c *config) {
+		c.debugHeader = name
+	}
+}
+
+// WithSlowThreshold logs the requests slower than d with the "slow" field set to true
+// and at Warn level, unless the status calls for a more severe level
+func WithSlowThreshold(d time.Duration) Option {
+	return func(c *config) {
+		c.slowThreshold = d
+	}
+}
+
+// WithBudgetWarning logs the requests whose Context has a deadline and that used more
+// than the given fraction of the time left before it, when they started, with the
+// "budget_exceeded" field set to true and at Warn level, unless the status calls
+// for a more severe level. E.g. 0.9 flags the requests that used 90% of their budget
+func WithBudgetWarning(fraction float64) Option {
+	return func(c *config) {
+		c.budgetWarn = fraction
+	}
+}
+
+// WithTTFB adds the time to first byte, from the start of the request to the moment
+// the header of the response was written, to the req_served line as the "ttfb_ms"
+// field in milliseconds. The field is omitted when no header was written,
+// e.g. when the connection was hijacked
+func WithTTFB() Option {
+	return func(c *config) {
+		c.ttfb = true
+	}
+}
+
+// WithClientDisconnectDetection logs the requests whose Context was canceled, usually
+// because the client went away, with the "client_disconnected" field set to true and
+// at Warn level. Requests whose Context deadline was exceeded get the "timeout" field instead
+func WithClientDisconnectDetection() Option {
+	return func(c *config) {
+		c.disconnects = true
+	}
+}
+
+// WithErrorFromContext calls f with the request Context once the request is served,
+// a non nil error is logged as the "error" field of the req_served line at Error level,
+// whatever the status. It surfaces the errors handlers record in the Context
+func WithErrorFromContext(f func(context.Context) error) Option {
+	return func(c *config) {
+		c.errorf = f
+	}
+}
+
+// WithErrorRateLimit logs at most n req_served lines at Error level, or more severe,
+// per window of the given duration and per response status. The lines over the limit
+// are dropped and their number is logged as the "suppressed" field of the next line
+// logged for the same status. Lines at less severe levels are never limited
+func WithErrorRateLimit(n int, per time.Duration) Option {
+	return func(c *config) {
+		c.errorLimit = &errorRateLimit{n: n, per: per, windows: make(map[string]*rateWindow)}
+	}
+}
+
+// WithErrorRateLimitKey sets the function returning the key under which the lines
+// are counted by WithErrorRateLimit, instead of the response status. Every key has
+// its own budget, so the function should only return a bounded set of keys
+func WithErrorRateLimitKey(f func(r *http.Request, status int) string) Option {
+	return func(c *config) {
+		c.errorKeyf = f
+	}
+}
+
+// WithSkipPaths disables logging for requests whose URL path exactly matches
+// one of the given paths. The match is case-sensitive and ignores the query string,
+// the request is still served as usual
+func WithSkipPaths(paths ...string) Option {
+	return func(c *config) {
+		if c.skipPaths == nil {
+			c.skipPaths = make(map[string]struct{}, len(paths))
+		}
+		for _, p := range paths {
+			c.skipPaths[p] = struct{}{}
+		}
+	}
+}
+
+// WithSkipPathsUnlessError disables logging for requests whose URL path exactly matches
+// one of the given paths, as WithSkipPaths does, unless they are answered with a 4xx
+// or 5xx status, e.g. to only log the failed health checks. The req_start line of
+// these requests is kept until the status is known
+func WithSkipPathsUnlessError(paths ...string) Option {
+	return func(c *config) {
+		if c.skipOKPaths == nil {
+			c.skipOKPaths = make(map[string]struct{}, len(paths))
+		}
+		for _, p := range paths {
+			c.skipOKPaths[p] = struct{}{}
+		}
+	}
+}
+
+// WithSkipFunc disables logging for the requests for which f returns true.
+// f is called once per request, before anything is logged, and the request
+// is still served as usual. It adds up to WithSkipPaths
+func WithSkipFunc(f func(*http.Request) bool) Option {
+	return func(c *config) {
+		c.skipFuncs = append(c.skipFuncs, f)
+	}
+}
+
+// WithoutStartLog disables the req_start line, only req_served is logged
+func WithoutStartLog() Option {
+	return func(c *config) {
+		c.noStartLog = true
+	}
+}
+
+// WithSingleLine logs a single req_served line per request, even for the requests
+// logged at Debug WithDebugHeader: the req_start line is never logged and its fields
+// that req_served lacks are added to req_served instead
+func WithSingleLine() Option {
+	return func(c *config) {
+		c.singleLine = true
+	}
+}
+
+// WithContextEntry stores a *logrus.Entry carrying the request fields in the
+// request Context, downstream handlers can retrieve it with FromContext
+func WithContextEntry() Option {
+	return func(c *config) {
+		c.ctxEntry = true
+	}
+}
+
+// WithUserAgent adds the User-Agent of the request to the req_served line
+// as the "user_agent" field. An empty string is logged when the header is missing
+func WithUserAgent() Option {
+	return func(c *config) {
+		c.userAgent = true
+	}
+}
+
+// WithReferer adds the Referer of the request to the req_served line as
+// the "referer" field, spelled as in the HTTP spec. An empty string is logged
+// when the header is missing
+func WithReferer() Option {
+	return func(c *config) {
+		c.referer = true
+	}
+}
+
+// WithHost adds the host targeted by the request, including the port if any,
+// to both log lines as the "host" field
+func WithHost() Option {
+	return func(c *config) {
+		c.host = true
+	}
+}
+
+// WithProto adds the protocol version negotiated for the request
+// (e.g. "HTTP/1.1" or "HTTP/2.0") to the req_served line as the "proto" field
+func WithProto() Option {
+	return func(c *config) {
+		c.proto = true
+	}
+}
+
+// WithSeparateQuery logs the path and the query string of the request as the
+// "path" and "query" fields instead of the combined "uri" field
+func WithSeparateQuery() Option {
+	return func(c *config) {
+		c.separateQuery = true
+	}
+}
+
+// WithFullURL also logs the absolute URL of the request, e.g. https://example.com/a?b=c,
+// as the "url" field of both lines. The scheme is the one logged WithScheme and the
+// query parameters are redacted as in "uri". Without a Host the URI is logged as is
+func WithFullURL() Option {
+	return func(c *config) {
+		c.urlField = true
+	}
+}
+
+// WithRedactedQueryParams replaces the values of the given query parameters
+// with REDACTED in the logged "uri" or "query" field. The request itself is not modified
+func WithRedactedQueryParams(keys ...string) Option {
+	return func(c *config) {
+		if c.redactParams == nil {
+			c.redactParams = make(map[string]struct{}, len(keys))
+		}
+		for _, k := range keys {
+			c.redactParams[k] = struct{}{}
+		}
+	}
+}
+
+// WithHeaders adds the given request headers to the req_served line. Each header
+// is logged under its canonical name prefixed with "header_", lowercased and with
+// dashes replaced by underscores (X-Tenant-ID is logged as "header_x_tenant_id").
+// Headers missing from the request are not logged
+func WithHeaders(names ...string) Option {
+	return func(c *config) {
+		for _, name := range names {
+			name = http.CanonicalHeaderKey(name)
+			c.setHeaderField(name, headerFieldName("header_", name))
+		}
+	}
+}
+
+// WithHeaderFields adds the request headers given as keys to the req_served line,
+// each one logged as the field given as its value instead of the WithHeaders name,
+// e.g. {"X-Tenant-ID": "tenant"}. Headers missing from the request are not logged
+func WithHeaderFields(fields map[string]string) Option {
+	return func(c *config) {
+		for name, field := range fields {
+			c.setHeaderField(http.CanonicalHeaderKey(name), field)
+		}
+	}
+}
+
+// WithContentLength adds the Content-Length of the request to the req_served line
+// as the "bytes_in" field. -1 is logged when the length is unknown
+func WithContentLength() Option {
+	return func(c *config) {
+		c.contentLength = true
+	}
+}
+
+// WithHumanSizes also logs the "bytes" field, and the "bytes_in" field configured
+// WithContentLength, as human readable sizes, e.g. "1.2 MB", in the "bytes_human"
+// and "bytes_in_human" fields. An unknown Content-Length has no human size
+func WithHumanSizes() Option {
+	return func(c *config) {
+		c.humanSizes = true
+	}
+}
+
+// WithResponseContentType adds the Content-Type of the response, as set when the
+// header was written, to the req_served line as the "content_type" field.
+// The field is omitted when the response has no Content-Type
+func WithResponseContentType() Option {
+	return func(c *config) {
+		c.respContentType = true
+	}
+}
+
+// WithNumericLatency logs the latency as the float64 "latency_ms" field,
+// in milliseconds, instead of the formatted "latency" string
+func WithNumericLatency() Option {
+	return func(c *config) {
+		c.latencyUnit = time.Millisecond
+	}
+}
+
+// WithLatencyUnit logs the latency as a float64 expressed in the given unit instead
+// of the formatted "latency" string. The field is named after the unit:
+// "latency_ns", "latency_us", "latency_ms" or "latency_s"
+func WithLatencyUnit(unit time.Duration) Option {
+	return func(c *config) {
+		c.latencyUnit = unit
+	}
+}
+
+// WithDurationLatency logs the latency as the "duration" field in the time.Duration
+// string form (e.g. "12.3456ms"), which can be parsed back with time.ParseDuration,
+// instead of the formatted "latency" string
+func WithDurationLatency() Option {
+	return func(c *config) {
+		c.durationLatency = true
+	}
+}
+
+// WithRecovery recovers from the panics of the handler. The panic is logged as req_panic
+// at Error level with the "panic" value, as a string, and the "stack" trace fields,
+// a 500 is written if the handler did not write the header yet and req_served is
+// logged at Error level with the "panic" field. http.ErrAbortHandler is re-panicked
+func WithRecovery() Option {
+	return func(c *config) {
+		c.recovery = true
+	}
+}
+
+// WithFieldNames renames the logged fields, the keys of the map are the default
+// field names (e.g. "status") and the values the names to log them as
+// (e.g. "http.status_code"). Fields that are not in the map keep their default name
+func WithFieldNames(names map[string]string) Option {
+	return func(c *config) {
+		if c.fieldNames == nil {
+			c.fieldNames = make(map[string]string, len(names))
+		}
+		for key, name := range names {
+			c.fieldNames[key] = name
+		}
+	}
+}
+
+// WithSampling logs only a fraction of the successful (1xx, 2xx and 3xx) requests,
+// each one being logged with a probability of rate, in (0,1].
+// 4xx and 5xx responses are always logged. Both lines of a request are either
+// logged or dropped together
+func WithSampling(rate float64) Option {
+	return func(c *config) {
+		c.sampleRate = rate
+	}
+}
+
+// WithSamplingByPath samples the successful requests, as WithSampling does, with a rate
+// depending on the URL path. Patterns ending with "*" match the paths starting with
+// the rest of the pattern, e.g. "/static/*", the others match a path exactly.
+// An exact match wins over the prefixes and the longest matching prefix wins
+// over the shorter ones. The paths matching no pattern use the WithSampling rate.
+// A rate of 0 drops all the successful requests of the matching paths
+//
+// Example:
+//
+//	glogrus.WithSamplingByPath(map[string]float64{
+//		"/api/*":        0.1,
+//		"/api/checkout": 1,
+//		"/healthz":      0,
+//	})
+func WithSamplingByPath(rates map[string]float64) Option {
+	return func(c *config) {
+		for pattern, rate := range rates {
+			c.pathRates = append(c.pathRates, newPathRate(pattern, rate))
+		}
+		sortPathRates(c.pathRates)
+	}
+}
+
+// WithErrorsOnly logs only the requests served with a status of at least minStatus,
+// 500 when minStatus is 0. The req_start line of a request is only logged, right
+// before req_served, once the request is served with such a status
+func WithErrorsOnly(minStatus int) Option {
+	if minStatus <= 0 {
+		minStatus = http.StatusInternalServerError
+	}
+	return func(c *config) {
+		c.minStatus = minStatus
+	}
+}
+
+// WithRequestFields adds the fields returned by f to both log lines.
+// f is called once per request, a nil result adds nothing.
+// It can be given several times, the fields are added in order
+func WithRequestFields(f func(*http.Request) logrus.Fields) Option {
+	return func(c *config) {
+		c.fieldFuncs = append(c.fieldFuncs, f)
+	}
+}
+
+// WithContextFields adds the fields returned by f from the request Context,
+// such as the tenant or the user identity, to both log lines.
+// f is called once per request, a nil result adds nothing
+func WithContextFields(f func(context.Context) logrus.Fields) Option {
+	return WithRequestFields(func(r *http.Request) logrus.Fields {
+		return f(r.Context())
+	})
+}
+
+// WithConditionalField adds the name field to both log lines, with the value returned
+// by value, for the requests for which predicate returns true. value is only called
+// for those requests, e.g. for an expensive geo lookup. It can be given several
+// times, the fields are added in order along with the WithRequestFields ones
+func WithConditionalField(name string, predicate func(*http.Request) bool, value func(*http.Request) interface{}) Option {
+	return WithRequestFields(func(r *http.Request) logrus.Fields {
+		if !predicate(r) {
+			return nil
+		}
+		return logrus.Fields{name: value(r)}
+	})
+}
+
+// WithCommonLogFormat replaces the req_start and req_served lines with a single line
+// per served request in the Apache Common Log Format, logged at Info level as the message:
+//
+//	remote - - [timestamp] "METHOD uri proto" status bytes
+func WithCommonLogFormat() Option {
+	return func(c *config) {
+		c.accessLog = &accessLog{format: commonLogFormat}
+	}
+}
+
+// WithCommonLogWriter is like WithCommonLogFormat but writes the lines to w
+// instead of logging them
+func WithCommonLogWriter(w io.Writer) Option {
+	return func(c *config) {
+		c.accessLog = &accessLog{format: commonLogFormat, w: w}
+	}
+}
+
+// WithCombinedLogFormat is like WithCommonLogFormat with the Apache Combined Log Format,
+// also carrying the user of the request URL, the referer and the user agent:
+//
+//	remote - user [timestamp] "METHOD uri proto" status bytes "referer" "user-agent"
+func WithCombinedLogFormat() Option {
+	return func(c *config) {
+		c.accessLog = &accessLog{format: combinedLogFormat}
+	}
+}
+
+// WithCombinedLogWriter is like WithCombinedLogFormat but writes the lines to w
+// instead of logging them
+func WithCombinedLogWriter(w io.Writer) Option {
+	return func(c *config) {
+		c.accessLog = &accessLog{format: combinedLogFormat, w: w}
+	}
+}
+
+// WithForwardedFor logs the left-most address of the X-Forwarded-For header, i.e. the
+// original client, as the "remote" field and the address of the direct peer as
+// the "proxy" field. When the header is missing the remote address is logged as usual.
+// The bare IP of the logged address, without port nor IPv6 brackets, is logged
+// as the "ip" field. The header can be restricted to trusted proxies WithTrustedProxies
+func WithForwardedFor() Option {
+	return func(c *config) {
+		c.forwardedFor = true
+	}
+}
+
+// WithTrustedProxies restricts the headers set by proxies, like X-Forwarded-For,
+// to requests whose direct peer is in one of the given CIDRs or bare IPs.
+// It panics if one of them is invalid
+func WithTrustedProxies(cidrs ...string) Option {
+	nets := parseCIDRs(cidrs)
+	return func(c *config) {
+		c.trustedProxies = append(c.trustedProxies, nets...)
+	}
+}
+
+// WithAccessLogWriter writes the req_start and req_served lines to w, through
+// a dedicated logger, instead of the logger given to the middleware. The dedicated
+// logger uses the formatter, the level and the fields of the main logger, unless
+// the formatter is overridden WithAccessLogFormatter
+func WithAccessLogWriter(w io.Writer) Option {
+	return func(c *config) {
+		c.accessWriter = w
+	}
+}
+
+// WithAccessLogFormatter sets the formatter of the logger writing WithAccessLogWriter
+func WithAccessLogFormatter(f logrus.Formatter) Option {
+	return func(c *config) {
+		c.accessFormatter = f
+	}
+}
+
+// WithTee writes the req_start and req_served lines twice, as text to human, e.g. the
+// console, and as JSON to machine, e.g. the file read by the log aggregator, both
+// with the same fields. It replaces WithAccessLogWriter and WithAccessLogFormatter:
+// the lines are written by two dedicated loggers inheriting the level and
+// the fields of the logger given to the middleware
+func WithTee(human, machine io.Writer) Option {
+	return func(c *config) {
+		c.teeWriter = human
+		c.accessWriter = machine
+		c.accessFormatter = new(logrus.JSONFormatter)
+	}
+}
+
+// WithDiscard writes the req_start and req_served lines to io.Discard, through
+// a logger inheriting the formatter and the level of the main one, instead of any
+// configured output. The request is still measured and its fields built and formatted,
+// e.g. to benchmark the handlers with the overhead of the middleware
+func WithDiscard() Option {
+	return func(c *config) {
+		c.discard = true
+	}
+}
+
+// WithObserver calls f with the measurements of every served request, right after
+// req_served is logged, e.g. to feed Prometheus metrics. f is also called for the
+// requests that are not logged, unless configured WithObserveLoggedOnly
+func WithObserver(f func(RequestInfo)) Option {
+	return func(c *config) {
+		c.observer = f
+	}
+}
+
+// WithObserveLoggedOnly restricts the observer set WithObserver to the requests
+// that are not skipped by WithSkipPaths or WithSkipFunc
+func WithObserveLoggedOnly() Option {
+	return func(c *config) {
+		c.observeLoggedOnly = true
+	}
+}
+
+// WithTLSInfo adds the negotiated TLS version (e.g. "TLS1.3") and cipher suite
+// to the req_served line as the "tls_version" and "tls_cipher" fields.
+// The fields are omitted for plaintext requests
+func WithTLSInfo() Option {
+	return func(c *config) {
+		c.tlsInfo = true
+	}
+}
+
+// WithStaticFields adds the given fields, such as the service name or version,
+// to both log lines. They override the built-in fields with the same name
+func WithStaticFields(fields logrus.Fields) Option {
+	return func(c *config) {
+		if c.staticFields == nil {
+			c.staticFields = make(logrus.Fields, len(fields))
+		}
+		addFields(c.staticFields, fields)
+	}
+}
+
+// WithEnvironment adds the "env" field with the deployment environment, e.g. "staging",
+// to both log lines. It wins over an "env" field set WithStaticFields
+func WithEnvironment(env string) Option {
+	return func(c *config) {
+		c.env = env
+	}
+}
+
+// WithSourceField adds the "source" field with the given value to both log lines,
+// telling apart the lines of several middlewares logging to the same stream.
+// The route of the request can be logged as well with glogrusgoji.WithPattern
+func WithSourceField(value string) Option {
+	return WithStaticFields(logrus.Fields{"source": value})
+}
+
+// WithBeforeHook calls f right before req_start is logged.
+// It can be given several times, the hooks are called in order
+func WithBeforeHook(f func(*http.Request)) Option {
+	return func(c *config) {
+		c.beforeHooks = append(c.beforeHooks, f)
+	}
+}
+
+// WithAfterHook calls f with the measurements of the request right after
+// req_served is logged. It is not called when the handler panics, unless
+// configured WithRecovery. It can be given several times, the hooks are called in order
+func WithAfterHook(f func(*http.Request, RequestInfo)) Option {
+	return func(c *config) {
+		c.afterHooks = append(c.afterHooks, f)
+	}
+}
+
+// WithScheme adds the scheme of the request, "https" or "http", to the req_served
+// line as the "scheme" field. The X-Forwarded-Proto header takes precedence only
+// when the peer is one of the proxies configured WithTrustedProxies, so that clients
+// cannot spoof "https": without trusted proxies the header is ignored
+func WithScheme() Option {
+	return func(c *config) {
+		c.schemeField = true
+	}
+}
+
+// WithRemotePort also logs the host and the port of the direct peer, i.e. the remote
+// address of the connection, as the "remote_host" and "remote_port" fields.
+// When the remote address has no port it is logged as a whole as "remote_host".
+// The bare IP of the "remote" field is logged as the "ip" field, as WithForwardedFor does
+func WithRemotePort() Option {
+	return func(c *config) {
+		c.remotePort = true
+	}
+}
+
+// WithoutRemoteAddr never logs the address of the client: the "remote" and "proxy"
+// fields, as well as the WithRemotePort ones, are omitted from both log lines,
+// the Common Log Format host is a dash and the headers carrying client addresses
+// (X-Forwarded-For, X-Real-IP, Forwarded, True-Client-IP, CF-Connecting-IP,
+// Fastly-Client-IP, X-Client-IP and X-Cluster-Client-IP) are dropped from
+// WithHeaders and WithHeaderFields
+func WithoutRemoteAddr() Option {
+	return func(c *config) {
+		c.noRemote = true
+	}
+}
+
+// WithResponseHeaders adds the given response headers, as set when the header was
+// written, to the req_served line. Each header is logged under its canonical name
+// prefixed with "resp_header_", lowercased and with dashes replaced by underscores
+// (Cache-Control is logged as "resp_header_cache_control").
+// Headers not set by the handler are not logged
+func WithResponseHeaders(names ...string) Option {
+	return func(c *config) {
+		for _, name := range names {
+			name = http.CanonicalHeaderKey(name)
+			c.respHeaders = append(c.respHeaders, headerField{
+				header: name,
+				field:  headerFieldName("resp_header_", name),
+			})
+			c.writer.snapshot = append(c.writer.snapshot, name)
+		}
+	}
+}
+
+// WithRedirectTarget adds the Location header of 3xx responses, as set when the header
+// was written, to the req_served line as the "location" field
+func WithRedirectTarget() Option {
+	return func(c *config) {
+		c.redirectTarget = true
+		c.writer.snapshot = append(c.writer.snapshot, "Location")
+	}
+}
+
+// WithFlushDetection adds the "flushed" field set to true to the req_served line of
+// the responses flushed by the handler, i.e. streamed rather than buffered, such as
+// server-sent events. Only flushes through the http.Flusher of the middleware count
+func WithFlushDetection() Option {
+	return func(c *config) {
+		c.flushes = true
+	}
+}
+
+// WithRequestBody adds up to maxBytes of the request body, as read by the handler,
+// to the req_served line as the "request_body" field. Longer bodies are truncated
+// and end with "...", bodies that are not valid UTF-8 are logged base64 encoded
+// as the "request_body_base64" field. The handler still reads the whole body
+func WithRequestBody(maxBytes int) Option {
+	return func(c *config) {
+		c.reqBodyMax = maxBytes
+	}
+}
+
+// WithResponseBody adds up to maxBytes of the response body to the req_served line
+// as the "response_body" field. Longer bodies are truncated and end with "...".
+// Only textual Content-Types, such as text/* or application/json, are logged
+func WithResponseBody(maxBytes int) Option {
+	return func(c *config) {
+		c.writer.bodyMax = maxBytes
+	}
+}
+
+// WithResponseBodyOnError restricts WithResponseBody to 4xx and 5xx responses
+func WithResponseBodyOnError() Option {
+	return func(c *config) {
+		c.writer.bodyOnError = true
+	}
+}
+
+// WithNanosecondLatency logs the latency as the int64 "latency_ns" field, in
+// nanoseconds, instead of the formatted "latency" string
+func WithNanosecondLatency() Option {
+	return func(c *config) {
+		c.nanoLatency = true
+	}
+}
+
+// WithLatencyBreakdown adds the time spent in the handler, measured right before and
+// after calling it, to the req_served line as the "handler_ms" field in milliseconds.
+// The rest of the latency is spent in the middleware itself, e.g. logging req_start
+func WithLatencyBreakdown() Option {
+	return func(c *config) {
+		c.handlerLatency = true
+	}
+}
+
+// WithDeadline adds the time left before the deadline of the request Context, when
+// the request started, to the req_served line as the "deadline_ms" field in milliseconds.
+// The field is omitted when the Context has no deadline
+func WithDeadline() Option {
+	return func(c *config) {
+		c.deadline = true
+	}
+}
+
+// WithCookies adds the names of the cookies of the request, joined with commas,
+// to the req_served line as the "cookies" field. Their values are only logged
+// WithCookieValues, as name=value, except for the cookies named in redact
+// whose values are replaced by REDACTED
+func WithCookies(redact ...string) Option {
+	return func(c *config) {
+		c.cookieNames = true
+		if c.redactCookies == nil {
+			c.redactCookies = make(map[string]struct{}, len(redact))
+		}
+		for _, name := range redact {
+			c.redactCookies[name] = struct{}{}
+		}
+	}
+}
+
+// WithCookieValues logs the values of the cookies along with their names
+// when configured WithCookies
+func WithCookieValues() Option {
+	return func(c *config) {
+		c.cookieValues = true
+	}
+}
+
+// WithContentEncoding adds the Content-Encoding of the response, e.g. "gzip", as set
+// when the header was written, to the req_served line as the "content_encoding" field.
+// For responses without Content-Encoding the field is "identity" when logIdentity is
+// true and omitted otherwise. It pairs well with WithUncompressedBytes
+func WithContentEncoding(logIdentity bool) Option {
+	return func(c *config) {
+		c.respEncoding = true
+		c.logIdentity = logIdentity
+	}
+}
+
+// WithUncompressedBytes adds the size of encoded (e.g. gzip) responses before
+// compression to the req_served line as the "bytes_uncompressed" field, as counted by
+// CountUncompressed installed after the compression middleware. The "bytes" field
+// keeps counting the bytes actually written. The field is omitted when it is not known
+func WithUncompressedBytes() Option {
+	return func(c *config) {
+		c.uncompressed = true
+	}
+}
+
+// WithAsync logs the req_start and req_served lines through a, from its background
+// goroutine, instead of logging them while serving the request
+//
+// Example:
+//
+//	async := glogrus.NewAsync(1024, glogrus.AsyncDrop)
+//	defer async.Close()
+//	goji.Use(glogrus.NewGlogrusWithOptions(logr, glogrus.WithAsync(async)))
+func WithAsync(a *Async) Option {
+	return func(c *config) {
+		c.async = a
+	}
+}
+
+// WithAdditionalLoggers also logs the req_start and req_served lines to each of the
+// given loggers, after the main one and with the same fields, e.g. to write JSON
+// lines for the log collector and text lines to the console during development
+func WithAdditionalLoggers(loggers ...logrus.FieldLogger) Option {
+	return func(c *config) {
+		c.extraLoggers = append(c.extraLoggers, loggers...)
+	}
+}
@@ -0,0 +1,48 @@
+package glogrus
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fieldsPool keeps the maps of the req_start and req_served fields between requests.
+//
+// A map can be reused as soon as its line is logged: WithFields of *logrus.Logger and
+// *logrus.Entry copies the fields into the data of the new entry, so that the hooks,
+// the formatter and the goroutine of WithAsync only ever see the copy. Any other
+// FieldLogger may keep the map, in that case the maps are not reused
+var fieldsPool = sync.Pool{
+	New: func() interface{} {
+		return make(logrus.Fields, 16)
+	},
+}
+
+// copiesFields reports whether WithFields of l is known to copy the fields
+func copiesFields(l logrus.FieldLogger) bool {
+	switch l.(type) {
+	case *logrus.Logger, *logrus.Entry:
+		return true
+	}
+	return false
+}
+
+// newFields returns an empty map for the fields of a line,
+// from the pool when the loggers copy the fields
+func (c *config) newFields() logrus.Fields {
+	if !c.reuseFields {
+		return make(logrus.Fields)
+	}
+	return fieldsPool.Get().(logrus.Fields)
+}
+
+// putFields empties the map of the fields of a logged line and puts it back in the pool
+func (c *config) putFields(fields logrus.Fields) {
+	if !c.reuseFields || fields == nil {
+		return
+	}
+	for key := range fields {
+		delete(fields, key)
+	}
+	fieldsPool.Put(fields)
+}
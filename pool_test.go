@@ -0,0 +1,104 @@
+package glogrus
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// syncBuffer is a bytes.Buffer safe for the concurrent writes of several loggers
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// TestFieldsPoolConcurrent checks that the pooled maps are never shared by two lines,
+// run it with -race
+func TestFieldsPoolConcurrent(t *testing.T) {
+	const requests = 200
+
+	var main, extra syncBuffer
+	newLogger := func(out *syncBuffer) *logrus.Logger {
+		l := logrus.New()
+		l.Out = out
+		l.Formatter = new(logrus.JSONFormatter)
+		return l
+	}
+	async := NewAsync(8, AsyncBlock)
+	for _, single := range []bool{false, true} {
+		opts := []Option{
+			WithAsync(async),
+			WithAdditionalLoggers(newLogger(&extra).WithField("copy", true)),
+			WithRecovery(),
+			WithRequestIDHeader("X-Id"),
+			WithStaticFields(logrus.Fields{"service": "test"}),
+		}
+		if single {
+			opts = append(opts, WithSingleLine())
+		}
+		h := NewGlogrusWithOptions(newLogger(&main), opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("panic") != "" {
+				panic("boom")
+			}
+		}))
+
+		var wg sync.WaitGroup
+		for i := 0; i < requests; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				id := strconv.Itoa(i)
+				uri := "/" + id
+				if i%3 == 0 {
+					uri += "?panic=1"
+				}
+				r := httptest.NewRequest("GET", uri, nil)
+				r.Header.Set("X-Id", id)
+				h.ServeHTTP(httptest.NewRecorder(), r)
+			}(i)
+		}
+		wg.Wait()
+	}
+	async.Close()
+
+	for name, out := range map[string]*syncBuffer{"main": &main, "extra": &extra} {
+		// req_panic is logged by the main logger only, outside of the pool
+		var lines []map[string]interface{}
+		for _, line := range logLines(t, &out.buf) {
+			if line["msg"] != "req_panic" {
+				lines = append(lines, line)
+			}
+		}
+		// both passes log every request, the first one with two lines
+		if want := 3 * requests; len(lines) != want {
+			t.Errorf("%s: got %d lines, want %d", name, len(lines), want)
+		}
+		for _, line := range lines {
+			id, _ := line["req_id"].(string)
+			uri, _ := line["uri"].(string)
+			if n := len("/" + id); id == "" || len(uri) < n || uri[:n] != "/"+id || len(uri) > n && uri[n] != '?' {
+				t.Errorf("%s: req_id %q logged with uri %q", name, id, uri)
+			}
+			if line["service"] != "test" {
+				t.Errorf("%s: missing static field in %v", name, line)
+			}
+			if _, copied := line["copy"]; copied != (name == "extra") {
+				t.Errorf("%s: copy field in %v", name, line)
+			}
+			if panicked := len(uri) > len("/"+id); line["msg"] == "req_served" && panicked != (line["panic"] == "boom") {
+				t.Errorf("%s: panic field in %v", name, line)
+			}
+		}
+	}
+}
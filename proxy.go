@@ -0,0 +1,161 @@
+package glogrus
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// parseCIDRs parses the trusted proxies, a bare IP is treated as a single host network
+func parseCIDRs(cidrs []string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				panic(fmt.Sprintf("glogrus: invalid trusted proxy %q", cidr))
+			}
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(fmt.Sprintf("glogrus: invalid trusted proxy %q: %v", cidr, err))
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// trustedPeer reports whether the headers set by proxies can be trusted for the request,
+// i.e. no trusted proxies are configured or the direct peer is one of them
+func (c *config) trustedPeer(r *http.Request) bool {
+	if len(c.trustedProxies) == 0 {
+		return true
+	}
+	host, _ := splitHostPort(r.RemoteAddr)
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range c.trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteAddr returns the address logged as the "remote" field and, when it was taken
+// from the X-Forwarded-For header, the address of the proxy logged as the "proxy" field.
+// Both are empty when configured WithoutRemoteAddr
+func (c *config) remoteAddr(r *http.Request) (remote, proxy string) {
+	if c.noRemote {
+		return "", ""
+	}
+	if !c.forwardedFor {
+		return r.RemoteAddr, ""
+	}
+	xff := r.Header.Get("X-Forwarded-For")
+	if xff == "" || !c.trustedPeer(r) {
+		return r.RemoteAddr, ""
+	}
+	if i := strings.IndexByte(xff, ','); i >= 0 {
+		xff = xff[:i]
+	}
+	client := strings.TrimSpace(xff)
+	if client == "" {
+		return r.RemoteAddr, ""
+	}
+	return client, r.RemoteAddr
+}
+
+// scheme returns "https" for TLS requests and "http" otherwise. The X-Forwarded-Proto
+// header, set by proxies terminating TLS, takes precedence when the peer is one of
+// the proxies configured WithTrustedProxies, it is ignored when none is configured.
+// Only its first value counts, as the one set by the proxy closest to the client,
+// and it is ignored unless it is either http or https
+func (c *config) scheme(r *http.Request) string {
+	trusted := len(c.trustedProxies) > 0 && c.trustedPeer(r)
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" && trusted {
+		if i := strings.IndexByte(proto, ','); i >= 0 {
+			proto = proto[:i]
+		}
+		switch proto = strings.ToLower(strings.TrimSpace(proto)); proto {
+		case "http", "https":
+			return proto
+		}
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// splitHostPort splits the address in its host, without the brackets of IPv6 addresses,
+// and its port. The port is empty when the address has none, e.g. a bare IP taken
+// from the X-Forwarded-For header
+func splitHostPort(addr string) (host, port string) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		if strings.HasPrefix(addr, "[") && strings.HasSuffix(addr, "]") {
+			return addr[1 : len(addr)-1], ""
+		}
+		return addr, ""
+	}
+	return host, port
+}
+
+// remoteIP returns the bare IP of the address, e.g. 2001:db8::1 for [2001:db8::1]:443,
+// empty when the address does not hold an IP
+func remoteIP(addr string) string {
+	host, _ := splitHostPort(addr)
+	if net.ParseIP(host) == nil {
+		return ""
+	}
+	return host
+}
+
+// addRemote adds the remote address, even when empty, and the proxy address, when known,
+// to the fields, and the host and port of the direct peer configured WithRemotePort.
+// The bare IP of the remote address is added as well when configured
+// WithForwardedFor or WithRemotePort. Nothing is added when configured WithoutRemoteAddr
+func (c *config) addRemote(fields logrus.Fields, r *http.Request, remote, proxy string) {
+	if c.noRemote {
+		return
+	}
+	fields["remote"] = remote
+	if ip := remoteIP(remote); ip != "" && (c.forwardedFor || c.remotePort) {
+		fields["ip"] = ip
+	}
+	if proxy != "" {
+		fields["proxy"] = proxy
+	}
+	if c.remotePort && r.RemoteAddr != "" {
+		host, port := splitHostPort(r.RemoteAddr)
+		fields["remote_host"] = host
+		if port != "" {
+			fields["remote_port"] = port
+		}
+	}
+}
+
+// addressHeaders are the request headers carrying client addresses,
+// never logged when configured WithoutRemoteAddr
+var addressHeaders = map[string]struct{}{
+	"X-Forwarded-For":     {},
+	"X-Real-Ip":           {},
+	"Forwarded":           {},
+	"True-Client-Ip":      {},
+	"Cf-Connecting-Ip":    {},
+	"Fastly-Client-Ip":    {},
+	"X-Client-Ip":         {},
+	"X-Cluster-Client-Ip": {},
+}
@@ -0,0 +1,131 @@
+package glogrus
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEmptyRemoteAddrIsLogged(t *testing.T) {
+	l, buf := newTestLogger()
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = ""
+	w := httptest.NewRecorder()
+	NewGlogrus(l, "app")(http.HandlerFunc(okHandler)).ServeHTTP(w, r)
+
+	lines := logLines(t, buf)
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	for _, line := range lines {
+		if v, ok := line["remote"]; !ok || v != "" {
+			t.Errorf("%v: remote = %v, %v, want empty", line["msg"], v, ok)
+		}
+	}
+}
+
+func TestWithoutRemoteAddr(t *testing.T) {
+	const client, peer = "203.0.113.7", "10.0.0.1"
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = peer + ":1234"
+	var names []string
+	for name := range addressHeaders {
+		r.Header.Set(name, client)
+		names = append(names, name)
+	}
+
+	l, buf := newTestLogger()
+	serve(l, okHandler, r,
+		WithoutRemoteAddr(),
+		WithForwardedFor(),
+		WithRemotePort(),
+		WithHeaders(names...),
+		WithHeaderFields(map[string]string{"True-Client-IP": "client"}),
+		WithContextEntry(),
+	)
+	lines := logLines(t, buf)
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	for _, line := range lines {
+		for key, v := range line {
+			if s, ok := v.(string); ok && (strings.Contains(s, client) || strings.Contains(s, peer)) {
+				t.Errorf("%v: field %q = %q leaks an address", line["msg"], key, s)
+			}
+		}
+	}
+
+	var clf bytes.Buffer
+	serve(l, okHandler, r, WithoutRemoteAddr(), WithCommonLogWriter(&clf))
+	if !strings.HasPrefix(clf.String(), "- - - [") {
+		t.Errorf("Common Log Format line = %q, want a dash host", clf.String())
+	}
+}
+
+func TestRemoteIP(t *testing.T) {
+	tests := []struct{ addr, want string }{
+		{"[2001:db8::1]:443", "2001:db8::1"},
+		{"[2001:db8::4]", "2001:db8::4"},
+		{"2001:db8::2", "2001:db8::2"},
+		{"1.2.3.4:5", "1.2.3.4"},
+		{"10.0.0.1", "10.0.0.1"},
+		{"example.com:80", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := remoteIP(tt.addr); got != tt.want {
+			t.Errorf("remoteIP(%q) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestIPField(t *testing.T) {
+	tests := []struct{ remoteAddr, forwardedFor, want string }{
+		{"[2001:db8::1]:443", "", "2001:db8::1"},
+		{"10.0.0.1:80", "2001:db8::2", "2001:db8::2"},
+		{"10.0.0.1:80", "[2001:db8::3]:8080", "2001:db8::3"},
+		{"10.0.0.1:80", "[2001:db8::4]", "2001:db8::4"},
+		{"10.0.0.1", "", "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remoteAddr
+		if tt.forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", tt.forwardedFor)
+		}
+		l, buf := newTestLogger()
+		serve(l, okHandler, r, WithForwardedFor())
+		if got := servedLine(t, buf)["ip"]; got != tt.want {
+			t.Errorf("%q, X-Forwarded-For %q: ip = %v, want %s", tt.remoteAddr, tt.forwardedFor, got, tt.want)
+		}
+	}
+}
+
+func TestSchemeForwardedProto(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		opts       []Option
+		want       string
+	}{
+		{"no trusted proxies", "203.0.113.7:1234", nil, "http"},
+		{"untrusted peer", "203.0.113.7:1234", []Option{WithTrustedProxies("10.0.0.0/8")}, "http"},
+		{"trusted peer", "10.0.0.1:1234", []Option{WithTrustedProxies("10.0.0.0/8")}, "https"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/a", nil)
+		r.RemoteAddr = tt.remoteAddr
+		r.Header.Set("X-Forwarded-Proto", "https")
+		l, buf := newTestLogger()
+		serve(l, okHandler, r, append(tt.opts, WithScheme(), WithFullURL())...)
+		line := servedLine(t, buf)
+		if line["scheme"] != tt.want {
+			t.Errorf("%s: scheme = %v, want %s", tt.name, line["scheme"], tt.want)
+		}
+		if url := tt.want + "://example.com/a"; line["url"] != url {
+			t.Errorf("%s: url = %v, want %s", tt.name, line["url"], url)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package glogrus
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// errorRateLimit caps the number of error lines logged per window and per key
+type errorRateLimit struct {
+	n   int
+	per time.Duration
+
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+}
+
+// rateWindow counts the lines of a key in the current window
+type rateWindow struct {
+	start      time.Time
+	count      int
+	suppressed int
+}
+
+// errorLimitKey returns the key under which a line is counted by the error rate limit,
+// the response status unless configured WithErrorRateLimitKey
+func (c *config) errorLimitKey(r *http.Request, status int) string {
+	if c.errorKeyf != nil {
+		return c.errorKeyf(r, status)
+	}
+	return strconv.Itoa(status)
+}
+
+// allow reports whether a line of the key can be logged at now and, if so,
+// returns the number of lines of the key suppressed since the last logged one
+func (rl *errorRateLimit) allow(key string, now time.Time) (bool, int) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	w, ok := rl.windows[key]
+	if !ok {
+		w = &rateWindow{start: now}
+		rl.windows[key] = w
+	}
+	if now.Sub(w.start) >= rl.per {
+		w.start = now
+		w.count = 0
+	}
+	if w.count >= rl.n {
+		w.suppressed++
+		return false, 0
+	}
+	w.count++
+	suppressed := w.suppressed
+	w.suppressed = 0
+	return true, suppressed
+}
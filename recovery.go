@@ -0,0 +1,40 @@
+package glogrus
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/sirupsen/logrus"
+)
+
+// serve calls the handler and, when configured WithRecovery, recovers from its panics.
+// A recovered panic is logged at Error level, with the panic value as a string and
+// the stack trace, and answered with a 500 when the header was not written yet
+// and the connection was not hijacked.
+// The panic value is returned, empty when the handler did not panic.
+// http.ErrAbortHandler is always re-panicked
+func (c *config) serve(h http.Handler, w writerProxy, r *http.Request, l logrus.FieldLogger, reqID string) (panicked string) {
+	if c.recovery {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			panicked = fmt.Sprint(rec)
+			c.withFields(l, logrus.Fields{
+				"req_id": reqID,
+				"panic":  panicked,
+				"stack":  string(debug.Stack()),
+			}).Error("req_panic")
+			if w.Status() == 0 {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}()
+	}
+	h.ServeHTTP(w, r)
+	return ""
+}
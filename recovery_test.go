@@ -0,0 +1,70 @@
+package glogrus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecovery(t *testing.T) {
+	l, buf := newTestLogger()
+	boom := func(w http.ResponseWriter, r *http.Request) { panic("boom") }
+	w := serve(l, boom, httptest.NewRequest("GET", "/", nil), WithRecovery())
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("code = %d, want 500", w.Code)
+	}
+
+	var panicLine map[string]interface{}
+	for _, line := range logLines(t, buf) {
+		if line["msg"] == "req_panic" {
+			panicLine = line
+		}
+	}
+	if panicLine == nil {
+		t.Fatalf("no req_panic line in %q", buf.String())
+	}
+	if panicLine["panic"] != "boom" {
+		t.Errorf("req_panic panic = %v, want boom", panicLine["panic"])
+	}
+	if stack, _ := panicLine["stack"].(string); !strings.HasPrefix(stack, "goroutine ") {
+		t.Errorf("req_panic stack = %q, want a goroutine dump", stack)
+	}
+
+	line := servedLine(t, buf)
+	if line["panic"] != "boom" {
+		t.Errorf("req_served panic = %v, want boom", line["panic"])
+	}
+	if line["status"] != float64(http.StatusInternalServerError) {
+		t.Errorf("req_served status = %v, want 500", line["status"])
+	}
+}
+
+func TestRecoveryAfterHijack(t *testing.T) {
+	l, buf := newTestLogger()
+	r := httptest.NewRequest("GET", "/ws", nil)
+	r.Header.Set("Connection", "Upgrade")
+	r.Header.Set("Upgrade", "websocket")
+	w := &hijackRecorder{headerRecorder{header: http.Header{}}}
+	NewGlogrusWithOptions(l, WithRecovery())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := w.(http.Hijacker).Hijack(); err != nil {
+			t.Fatal(err)
+		}
+		panic("boom")
+	})).ServeHTTP(w, r)
+
+	if len(w.codes) != 0 {
+		t.Errorf("statuses written after the hijack: %v", w.codes)
+	}
+	panicked := false
+	for _, line := range logLines(t, buf) {
+		panicked = panicked || line["msg"] == "req_panic" && line["panic"] == "boom"
+	}
+	if !panicked {
+		t.Errorf("no req_panic line in %q", buf.String())
+	}
+	line := servedLine(t, buf)
+	if line["status"] != float64(http.StatusSwitchingProtocols) || line["upgraded"] != true {
+		t.Errorf("req_served status = %v, upgraded = %v, want 101 and true", line["status"], line["upgraded"])
+	}
+}
@@ -0,0 +1,70 @@
+package glogrus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// defaultRequestIDHeader is the request header read when the Context carries no request id
+const defaultRequestIDHeader = "X-Request-ID"
+
+// requestID returns the request id found in the Context of the request,
+// falling back to the configured request header when it is empty.
+// When both are empty and a generator is configured a new id is generated
+// and set on the response as the X-Request-ID header
+func (c *config) requestID(w http.ResponseWriter, r *http.Request) string {
+	if id := c.reqidf(r.Context()); id != "" {
+		return id
+	}
+	if c.reqIDHeader != "" {
+		if id := r.Header.Get(c.reqIDHeader); id != "" {
+			return id
+		}
+	}
+	if c.reqIDGen != nil {
+		id := c.reqIDGen()
+		w.Header().Set(defaultRequestIDHeader, id)
+		return id
+	}
+	return ""
+}
+
+// RequestIDFromContext returns a function retrieving the request id stored in the Context
+// under key, e.g. by a request id middleware, to be given to NewGlogrusWithReqId or
+// WithRequestID. The value must be a string or a fmt.Stringer, the id is empty otherwise
+//
+// Example:
+//
+//	goji.Use(glogrus.NewGlogrusWithReqId(logr, "my-app-name", glogrus.RequestIDFromContext(reqIDKey)))
+func RequestIDFromContext(key interface{}) func(context.Context) string {
+	return func(ctx context.Context) string {
+		switch v := ctx.Value(key).(type) {
+		case string:
+			return v
+		case fmt.Stringer:
+			return v.String()
+		}
+		return ""
+	}
+}
+
+// newRequestID returns a random 16 bytes request id encoded as hex
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// requestSeq counts the requests logged WithSequenceField by the process
+var requestSeq int64
+
+// nextSeq returns the sequence number of a new request, starting at 1
+func nextSeq() int64 {
+	return atomic.AddInt64(&requestSeq, 1)
+}
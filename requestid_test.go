@@ -0,0 +1,44 @@
+package glogrus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNilRequestID(t *testing.T) {
+	l, buf := newTestLogger()
+	w := httptest.NewRecorder()
+	NewGlogrusWithReqId(l, "app", nil)(http.HandlerFunc(okHandler)).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	line := servedLine(t, buf)
+	if id, ok := line["req_id"]; !ok || id != "" {
+		t.Errorf("req_id = %v, want empty", id)
+	}
+}
+
+type requestIDKey struct{}
+
+func TestRequestIDFromContext(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"string", "abc", "abc"},
+		{"stringer", 1500 * time.Millisecond, "1.5s"},
+		{"missing", nil, ""},
+		{"wrong type", 42, ""},
+	}
+	reqidf := RequestIDFromContext(requestIDKey{})
+	for _, tt := range tests {
+		ctx := context.Background()
+		if tt.value != nil {
+			ctx = context.WithValue(ctx, requestIDKey{}, tt.value)
+		}
+		if got := reqidf(ctx); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package glogrus
+
+import (
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// pathRate is a sample rate set WithSamplingByPath
+type pathRate struct {
+	pattern string
+	prefix  bool
+	rate    float64
+}
+
+// newPathRate returns the rate of the pattern, a prefix when it ends with "*"
+func newPathRate(pattern string, rate float64) pathRate {
+	if strings.HasSuffix(pattern, "*") {
+		return pathRate{pattern: strings.TrimSuffix(pattern, "*"), prefix: true, rate: rate}
+	}
+	return pathRate{pattern: pattern, rate: rate}
+}
+
+// sample reports whether a successful request is logged when configured WithSampling
+// or WithSamplingByPath
+func (c *config) sample(r *http.Request) bool {
+	rate, ok := c.pathSampleRate(r.URL.Path)
+	if !ok {
+		rate = c.sampleRate
+		if rate <= 0 {
+			return true
+		}
+	}
+	return rate >= 1 || rate > 0 && rand.Float64() < rate
+}
+
+// pathSampleRate returns the sample rate set WithSamplingByPath for the path:
+// the rate of the exact pattern, or else of the longest matching prefix
+func (c *config) pathSampleRate(path string) (float64, bool) {
+	for _, pr := range c.pathRates {
+		if pr.prefix && strings.HasPrefix(path, pr.pattern) || pr.pattern == path {
+			return pr.rate, true
+		}
+	}
+	return 0, false
+}
+
+// sortPathRates orders the rates so that the first matching one wins:
+// the exact patterns first, then the prefixes from the longest
+func sortPathRates(rates []pathRate) {
+	sort.SliceStable(rates, func(i, j int) bool {
+		if rates[i].prefix != rates[j].prefix {
+			return !rates[i].prefix
+		}
+		return len(rates[i].pattern) > len(rates[j].pattern)
+	})
+}
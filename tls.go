@@ -0,0 +1,34 @@
+package glogrus
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// tlsVersionName returns a readable name of the TLS version, e.g. "TLS1.3"
+func tlsVersionName(v uint16) string {
+	switch v {
+	case tls.VersionSSL30:
+		return "SSL3.0"
+	case tls.VersionTLS10:
+		return "TLS1.0"
+	case tls.VersionTLS11:
+		return "TLS1.1"
+	case tls.VersionTLS12:
+		return "TLS1.2"
+	case tls.VersionTLS13:
+		return "TLS1.3"
+	}
+	return fmt.Sprintf("0x%04X", v)
+}
+
+// addTLS adds the negotiated TLS version and cipher suite to the fields
+func addTLS(fields logrus.Fields, cs *tls.ConnectionState) {
+	if cs == nil {
+		return
+	}
+	fields["tls_version"] = tlsVersionName(cs.Version)
+	fields["tls_cipher"] = tls.CipherSuiteName(cs.CipherSuite)
+}
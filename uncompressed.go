@@ -0,0 +1,54 @@
+package glogrus
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// uncompressedKey is the Context key of the uncompressedCount of the request
+type uncompressedKey struct{}
+
+// uncompressedCount holds the number of bytes written by the handler
+// before compression, as counted by CountUncompressed
+type uncompressedCount struct {
+	bytes   int
+	counted bool
+}
+
+// CountUncompressed counts the bytes written by the next handlers before they are
+// compressed, for the middleware configured WithUncompressedBytes. It must be
+// installed between the compression middleware and the handler, as the bytes
+// reaching the glogrus middleware are already compressed:
+//
+//	logged := glogrus.NewGlogrusWithOptions(logr, glogrus.WithUncompressedBytes())
+//	http.Handle("/", logged(gzipMiddleware(glogrus.CountUncompressed(yourHandler))))
+//
+// The next handlers are called as they are when the request is not logged
+// WithUncompressedBytes
+func CountUncompressed(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uc, ok := r.Context().Value(uncompressedKey{}).(*uncompressedCount)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		lw := wrapWriter(w, &writerOptions{})
+		defer func() {
+			uc.bytes, uc.counted = lw.BytesWritten(), true
+		}()
+		next.ServeHTTP(lw, r)
+	})
+}
+
+// addTo adds the bytes counted before compression to the fields, for encoded
+// responses. The field is omitted when the response is not encoded or the bytes
+// were not counted, e.g. when CountUncompressed is not installed
+func (uc *uncompressedCount) addTo(fields logrus.Fields, w writerProxy) {
+	enc := w.contentEncoding()
+	if enc == "" || strings.EqualFold(enc, "identity") || !uc.counted {
+		return
+	}
+	fields["bytes_uncompressed"] = uc.bytes
+}
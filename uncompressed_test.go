@@ -0,0 +1,69 @@
+package glogrus
+
+import (
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// gzipWriter compresses the body written to the ResponseWriter
+type gzipWriter struct {
+	http.ResponseWriter
+	gz *gzip.Writer
+}
+
+func (g *gzipWriter) WriteHeader(code int) {
+	g.Header().Del("Content-Length")
+	g.Header().Set("Content-Encoding", "gzip")
+	g.ResponseWriter.WriteHeader(code)
+}
+
+func (g *gzipWriter) Write(b []byte) (int, error) { return g.gz.Write(b) }
+
+// gzipMiddleware compresses the responses, as the usual compression middlewares do
+func gzipMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gw := &gzipWriter{ResponseWriter: w, gz: gzip.NewWriter(w)}
+		next.ServeHTTP(gw, r)
+		gw.gz.Close()
+	})
+}
+
+func TestUncompressedBytes(t *testing.T) {
+	body := strings.Repeat("glogrus ", 1000)
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(body))
+	})
+
+	l, buf := newTestLogger()
+	w := httptest.NewRecorder()
+	NewGlogrusWithOptions(l, WithUncompressedBytes())(gzipMiddleware(CountUncompressed(h))).
+		ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	line := servedLine(t, buf)
+	if line["bytes"] != float64(w.Body.Len()) {
+		t.Errorf("bytes = %v, want the %d compressed bytes", line["bytes"], w.Body.Len())
+	}
+	if line["bytes_uncompressed"] == line["bytes"] {
+		t.Errorf("bytes_uncompressed = bytes = %v", line["bytes"])
+	}
+	if line["bytes_uncompressed"] != float64(len(body)) {
+		t.Errorf("bytes_uncompressed = %v, want %d", line["bytes_uncompressed"], len(body))
+	}
+}
+
+func TestUncompressedBytesNotCounted(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("hello")) })
+
+	l, buf := newTestLogger()
+	NewGlogrusWithOptions(l, WithUncompressedBytes())(gzipMiddleware(h)).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if v, ok := servedLine(t, buf)["bytes_uncompressed"]; ok {
+		t.Errorf("bytes_uncompressed = %v without CountUncompressed", v)
+	}
+}
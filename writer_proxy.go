@@ -1,20 +1,87 @@
 package glogrus
 
 import (
+	"bufio"
+	"io"
+	"net"
 	"net/http"
+	"time"
 )
 
-// wrapWriter returns a proxy that wraps ResponseWriter
-func wrapWriter(w http.ResponseWriter) writerProxy {
-	bw := basicWriter{ResponseWriter: w}
+// writerOptions configures what the proxy records of the response
+// besides the status and the number of bytes written
+type writerOptions struct {
+	// snapshot lists the response headers copied when the header is written
+	snapshot []string
+	// bodyMax is the number of bytes of the response body kept, if any
+	bodyMax int
+	// bodyOnError keeps the response body only for 4xx and 5xx responses
+	bodyOnError bool
+	// now is the clock of the hijack time, read only when the connection is hijacked
+	now func() time.Time
+	// recordFirstByte records when the header is written, as the first byte time
+	recordFirstByte bool
+}
+
+// wrapWriter returns a proxy that wraps ResponseWriter.
+// The proxy only implements the optional interfaces (http.Flusher, ...)
+// that are implemented by the wrapped ResponseWriter
+func wrapWriter(w http.ResponseWriter, opts *writerOptions) writerProxy {
+	_, fl := w.(http.Flusher)
+	_, hj := w.(http.Hijacker)
+	_, ps := w.(http.Pusher)
+
+	bw := basicWriter{ResponseWriter: w, opts: opts}
+	if fl && hj {
+		return &fancyWriter{bw}
+	}
+	if fl && ps {
+		return &http2FancyWriter{bw}
+	}
+	if fl {
+		return &flushWriter{bw}
+	}
+	if hj {
+		return &hijackWriter{bw}
+	}
+	if ps {
+		return &pushWriter{bw}
+	}
 	return &bw
 }
 
+// ResponseInfo is implemented by the ResponseWriter given to the handlers wrapped by
+// the middleware. A middleware installed after this one can read the response
+// written by the next handlers once they return:
+//
+//	func(next http.Handler) http.Handler {
+//		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//			next.ServeHTTP(w, r)
+//			if ri, ok := w.(glogrus.ResponseInfo); ok {
+//				metrics.Observe(ri.Status(), ri.BytesWritten())
+//			}
+//		})
+//	}
+type ResponseInfo interface {
+	// Status returns the status written so far, 0 when the header is not written yet
+	Status() int
+	// BytesWritten returns the number of bytes of the response body written so far
+	BytesWritten() int
+}
+
 // writerProxy is a proxy that wraps ResponseWriter
 type writerProxy interface {
 	http.ResponseWriter
+	ResponseInfo
 	maybeWriteHeader()
-	status() int
+	contentType() string
+	contentEncoding() string
+	headers() http.Header
+	body() *bodyBuffer
+	firstByte() time.Time
+	flushed() bool
+	hijackedAt() time.Time
+	Unwrap() http.ResponseWriter
 }
 
 // basicWriter holds the status code and a
@@ -23,12 +90,40 @@ type basicWriter struct {
 	http.ResponseWriter
 	wroteHeader bool
 	code        int
+	bytes       int
+	hijacked    bool
+	ctype       string
+	cencoding   string
+	opts        *writerOptions
+	snapHeader  http.Header
+	bodyBuf     *bodyBuffer
+	firstAt     time.Time
+	didFlush    bool
+	hijackAt    time.Time
 }
 
-// WriteHeader stores the status code and writes header
+// WriteHeader stores the status code and the Content-* headers and writes header.
+// Only the first final status is stored and forwarded, the later calls are ignored,
+// while informational 1xx statuses (e.g. 103 Early Hints) are forwarded as they are.
+// Nothing is written once the connection has been hijacked
 func (b *basicWriter) WriteHeader(code int) {
+	if b.hijacked {
+		return
+	}
+	if b.opts.recordFirstByte && b.firstAt.IsZero() {
+		b.firstAt = b.opts.now()
+	}
+	if !b.wroteHeader && code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		b.ResponseWriter.WriteHeader(code)
+		return
+	}
 	if !b.wroteHeader {
 		b.code = code
+		h := b.ResponseWriter.Header()
+		b.ctype = h.Get("Content-Type")
+		b.cencoding = h.Get("Content-Encoding")
+		b.snapshotHeaders()
+		b.keepBody()
 		b.wroteHeader = true
 		b.ResponseWriter.WriteHeader(code)
 	}
@@ -37,22 +132,207 @@ func (b *basicWriter) WriteHeader(code int) {
 // Write writes the bytes and calls MaybeWriteHeader
 func (b *basicWriter) Write(buf []byte) (int, error) {
 	b.maybeWriteHeader()
-	return b.ResponseWriter.Write(buf)
+	n, err := b.ResponseWriter.Write(buf)
+	b.bytes += n
+	if b.bodyBuf != nil {
+		b.bodyBuf.write(buf[:n])
+	}
+	return n, err
 }
 
 // maybeWriteHeader writes the header if it is not alredy set
+// and the connection has not been hijacked
 func (b *basicWriter) maybeWriteHeader() {
-	if !b.wroteHeader {
+	if !b.wroteHeader && !b.hijacked {
 		b.WriteHeader(http.StatusOK)
 	}
 }
 
-// status returns the status
-func (b *basicWriter) status() int {
+// Status returns the status written so far, it implements ResponseInfo. Once the handler
+// returns it is 200 as well when the handler wrote the body, or nothing, without calling
+// WriteHeader, like the wrapped ResponseWriter does
+func (b *basicWriter) Status() int {
 	return b.code
 }
 
-// unwrap returns the original http.ResponseWriter
+// BytesWritten returns the number of bytes written so far, it implements ResponseInfo
+func (b *basicWriter) BytesWritten() int {
+	return b.bytes
+}
+
+// contentType returns the Content-Type of the response when the header was written
+func (b *basicWriter) contentType() string {
+	return b.ctype
+}
+
+// snapshotHeaders copies the response headers listed in the options
+// as they are when the header is written
+func (b *basicWriter) snapshotHeaders() {
+	if len(b.opts.snapshot) == 0 {
+		return
+	}
+	h := b.ResponseWriter.Header()
+	b.snapHeader = make(http.Header, len(b.opts.snapshot))
+	for _, name := range b.opts.snapshot {
+		if v, ok := h[name]; ok {
+			b.snapHeader[name] = append([]string(nil), v...)
+		}
+	}
+}
+
+// contentEncoding returns the Content-Encoding of the response when the header was written
+func (b *basicWriter) contentEncoding() string {
+	return b.cencoding
+}
+
+// headers returns the snapshot of the response headers
+func (b *basicWriter) headers() http.Header {
+	return b.snapHeader
+}
+
+// keepBody starts keeping the response body when the options ask for it,
+// the status matches and the Content-Type is textual
+func (b *basicWriter) keepBody() {
+	if b.opts.bodyMax <= 0 || b.opts.bodyOnError && b.code < 400 || !textContentType(b.ctype) {
+		return
+	}
+	b.bodyBuf = &bodyBuffer{max: b.opts.bodyMax}
+}
+
+// body returns the kept response body, nil when it is not kept
+func (b *basicWriter) body() *bodyBuffer {
+	return b.bodyBuf
+}
+
+// firstByte returns when the header, the first byte of the response, was written.
+// It is zero when the header was not written or the options do not record it
+func (b *basicWriter) firstByte() time.Time {
+	return b.firstAt
+}
+
+// flushed reports whether the response was flushed by the handler
+func (b *basicWriter) flushed() bool {
+	return b.didFlush
+}
+
+// hijackedAt returns when the connection was hijacked, zero when it was not
+func (b *basicWriter) hijackedAt() time.Time {
+	return b.hijackAt
+}
+
+// hijack hijacks the connection of the wrapped ResponseWriter, which must be a http.Hijacker,
+// and records it so that the header is not written afterwards
+func (b *basicWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj := b.ResponseWriter.(http.Hijacker)
+	conn, rw, err := hj.Hijack()
+	if err == nil {
+		b.hijacked = true
+		if b.opts.now != nil {
+			b.hijackAt = b.opts.now()
+		}
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the original http.ResponseWriter, it lets http.ResponseController
+// reach the features of the wrapped ResponseWriter (Flush, Hijack, deadlines...)
 func (b *basicWriter) Unwrap() http.ResponseWriter {
 	return b.ResponseWriter
 }
+
+// flushWriter is a basicWriter that also implements http.Flusher
+type flushWriter struct {
+	basicWriter
+}
+
+// Flush writes the header if needed and flushes the buffered data to the client
+func (f *flushWriter) Flush() {
+	f.maybeWriteHeader()
+	fl := f.basicWriter.ResponseWriter.(http.Flusher)
+	fl.Flush()
+	f.didFlush = true
+}
+
+var _ http.Flusher = &flushWriter{}
+
+// hijackWriter is a basicWriter that also implements http.Hijacker
+type hijackWriter struct {
+	basicWriter
+}
+
+// Hijack lets the caller take over the connection
+func (f *hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return f.hijack()
+}
+
+var _ http.Hijacker = &hijackWriter{}
+
+// fancyWriter is a basicWriter that also implements
+// http.Flusher, http.Hijacker and io.ReaderFrom
+type fancyWriter struct {
+	basicWriter
+}
+
+// Flush writes the header if needed and flushes the buffered data to the client
+func (f *fancyWriter) Flush() {
+	f.maybeWriteHeader()
+	fl := f.basicWriter.ResponseWriter.(http.Flusher)
+	fl.Flush()
+	f.didFlush = true
+}
+
+// Hijack lets the caller take over the connection
+func (f *fancyWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return f.hijack()
+}
+
+// ReadFrom copies r to the response, using the ReadFrom of the
+// wrapped ResponseWriter (e.g. sendfile) when it is available
+func (f *fancyWriter) ReadFrom(r io.Reader) (int64, error) {
+	f.maybeWriteHeader()
+	rf, ok := f.basicWriter.ResponseWriter.(io.ReaderFrom)
+	if !ok || f.bodyBuf != nil {
+		return io.Copy(&f.basicWriter, r)
+	}
+	n, err := rf.ReadFrom(r)
+	f.bytes += int(n)
+	return n, err
+}
+
+var _ http.Flusher = &fancyWriter{}
+var _ http.Hijacker = &fancyWriter{}
+var _ io.ReaderFrom = &fancyWriter{}
+
+// http2FancyWriter is a basicWriter that also implements
+// http.Flusher and http.Pusher
+type http2FancyWriter struct {
+	basicWriter
+}
+
+// Flush writes the header if needed and flushes the buffered data to the client
+func (f *http2FancyWriter) Flush() {
+	f.maybeWriteHeader()
+	fl := f.basicWriter.ResponseWriter.(http.Flusher)
+	fl.Flush()
+	f.didFlush = true
+}
+
+// Push initiates an HTTP/2 server push
+func (f *http2FancyWriter) Push(target string, opts *http.PushOptions) error {
+	return f.basicWriter.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+var _ http.Flusher = &http2FancyWriter{}
+var _ http.Pusher = &http2FancyWriter{}
+
+// pushWriter is a basicWriter that also implements http.Pusher
+type pushWriter struct {
+	basicWriter
+}
+
+// Push initiates an HTTP/2 server push
+func (f *pushWriter) Push(target string, opts *http.PushOptions) error {
+	return f.basicWriter.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+var _ http.Pusher = &pushWriter{}
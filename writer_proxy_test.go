@@ -0,0 +1,198 @@
+package glogrus
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTrailers(t *testing.T) {
+	l, _ := newTestLogger()
+	srv := httptest.NewServer(NewGlogrus(l, "app")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Checksum")
+		w.Write([]byte("body"))
+		w.(http.Flusher).Flush()
+		w.Header().Set("X-Checksum", "abc")
+		w.Header().Set(http.TrailerPrefix+"X-Late", "def")
+	})))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if _, err := io.ReadAll(resp.Body); err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Trailer.Get("X-Checksum"); got != "abc" {
+		t.Errorf("declared trailer = %q, want abc", got)
+	}
+	if got := resp.Trailer.Get("X-Late"); got != "def" {
+		t.Errorf("TrailerPrefix trailer = %q, want def", got)
+	}
+}
+
+func TestImplicitStatus(t *testing.T) {
+	handlers := map[string]http.HandlerFunc{
+		"write": func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) },
+		"empty": okHandler,
+	}
+	for name, h := range handlers {
+		l, buf := newTestLogger()
+		serve(l, h, httptest.NewRequest("GET", "/", nil))
+		if got := servedLine(t, buf)["status"]; got != float64(http.StatusOK) {
+			t.Errorf("%s: status = %v, want 200", name, got)
+		}
+	}
+}
+
+// headerRecorder records the statuses written by the middleware
+type headerRecorder struct {
+	header http.Header
+	codes  []int
+}
+
+func (h *headerRecorder) Header() http.Header         { return h.header }
+func (h *headerRecorder) Write(b []byte) (int, error) { return len(b), nil }
+func (h *headerRecorder) WriteHeader(code int)        { h.codes = append(h.codes, code) }
+
+func TestWriteHeaderOnce(t *testing.T) {
+	l, buf := newTestLogger()
+	w := &headerRecorder{header: http.Header{}}
+	NewGlogrus(l, "app")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusEarlyHints)
+		w.WriteHeader(http.StatusNotFound)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("not found"))
+	})).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if len(w.codes) != 2 || w.codes[0] != http.StatusEarlyHints || w.codes[1] != http.StatusNotFound {
+		t.Errorf("underlying statuses = %v, want [103 404]", w.codes)
+	}
+	if got := servedLine(t, buf)["status"]; got != float64(http.StatusNotFound) {
+		t.Errorf("logged status = %v, want 404", got)
+	}
+}
+
+type flushRecorder struct{ headerRecorder }
+
+func (flushRecorder) Flush() {}
+
+type hijackRecorder struct{ headerRecorder }
+
+func (hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) { return nil, nil, nil }
+
+type pushRecorder struct {
+	headerRecorder
+	targets []string
+}
+
+func (p *pushRecorder) Push(target string, opts *http.PushOptions) error {
+	p.targets = append(p.targets, target)
+	return nil
+}
+
+type fancyRecorder struct {
+	flushRecorder
+	hijackRecorder
+}
+
+func (f *fancyRecorder) Header() http.Header         { return f.flushRecorder.Header() }
+func (f *fancyRecorder) Write(b []byte) (int, error) { return f.flushRecorder.Write(b) }
+func (f *fancyRecorder) WriteHeader(code int)        { f.flushRecorder.WriteHeader(code) }
+
+func TestWrapWriterInterfaces(t *testing.T) {
+	tests := map[string]http.ResponseWriter{
+		"basic":  &headerRecorder{header: http.Header{}},
+		"flush":  &flushRecorder{headerRecorder{header: http.Header{}}},
+		"hijack": &hijackRecorder{headerRecorder{header: http.Header{}}},
+		"fancy":  &fancyRecorder{},
+		"push":   &pushRecorder{headerRecorder: headerRecorder{header: http.Header{}}},
+	}
+	for name, w := range tests {
+		wrapped := wrapWriter(w, &writerOptions{})
+		_, fl := w.(http.Flusher)
+		_, hj := w.(http.Hijacker)
+		_, ps := w.(http.Pusher)
+		if _, ok := wrapped.(http.Pusher); ok != ps {
+			t.Errorf("%s: wrapped is http.Pusher = %v, want %v", name, ok, ps)
+		}
+		if _, ok := wrapped.(http.Flusher); ok != fl {
+			t.Errorf("%s: wrapped is http.Flusher = %v, want %v", name, ok, fl)
+		}
+		if _, ok := wrapped.(http.Hijacker); ok != hj {
+			t.Errorf("%s: wrapped is http.Hijacker = %v, want %v", name, ok, hj)
+		}
+	}
+}
+
+func TestHijackOnly(t *testing.T) {
+	l, _ := newTestLogger()
+	w := &hijackRecorder{headerRecorder{header: http.Header{}}}
+	NewGlogrus(l, "app")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			t.Fatal("the ResponseWriter is not a http.Hijacker")
+		}
+		if _, _, err := hj.Hijack(); err != nil {
+			t.Fatal(err)
+		}
+	})).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if len(w.codes) != 0 {
+		t.Errorf("statuses written after the hijack: %v", w.codes)
+	}
+}
+
+func TestPushOnly(t *testing.T) {
+	l, _ := newTestLogger()
+	w := &pushRecorder{headerRecorder: headerRecorder{header: http.Header{}}}
+	NewGlogrus(l, "app")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ps, ok := w.(http.Pusher)
+		if !ok {
+			t.Fatal("the ResponseWriter is not a http.Pusher")
+		}
+		if err := ps.Push("/style.css", nil); err != nil {
+			t.Fatal(err)
+		}
+	})).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if len(w.targets) != 1 || w.targets[0] != "/style.css" {
+		t.Errorf("pushed targets = %v, want [/style.css]", w.targets)
+	}
+}
+
+func TestHTTP2Push(t *testing.T) {
+	l, buf := newTestLogger()
+	pushErr := make(chan error, 1)
+	srv := httptest.NewUnstartedServer(NewGlogrus(l, "app")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ps, ok := w.(http.Pusher)
+		if !ok {
+			pushErr <- errors.New("the ResponseWriter is not a http.Pusher")
+			return
+		}
+		// the Go client disables push, the server reports it as not supported
+		pushErr <- ps.Push("/style.css", nil)
+	})))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("protocol = %s, want HTTP/2", resp.Proto)
+	}
+	if err := <-pushErr; !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("Push error = %v, want http.ErrNotSupported", err)
+	}
+	if got := servedLine(t, buf)["status"]; got != float64(http.StatusOK) {
+		t.Errorf("status = %v, want 200", got)
+	}
+}